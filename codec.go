@@ -0,0 +1,71 @@
+package respond
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// Encoder marshals the values you respond with as JSON. By default, responders use encoding/json,
+// but you can plug in a faster implementation (e.g. a code-generated marshaler) w/ WithEncoder().
+// Encoders should produce the same JSON that encoding/json would, including honoring MarshalJSON().
+type Encoder interface {
+	// Marshal returns the JSON encoding of the value.
+	Marshal(value interface{}) ([]byte, error)
+}
+
+// EncoderFunc is an adapter that lets you use an ordinary function (e.g. json.Marshal) as an Encoder.
+type EncoderFunc func(value interface{}) ([]byte, error)
+
+// Marshal calls the function to encode the value.
+func (fn EncoderFunc) Marshal(value interface{}) ([]byte, error) {
+	return fn(value)
+}
+
+// JSONAppender is a value that can append its own JSON encoding to a byte slice, which lets you
+// avoid reflection and extra allocations entirely. When you respond w/ one (or w/ anything that
+// contains one), we use it directly rather than going through the factory's Encoder.
+type JSONAppender interface {
+	// AppendJSON appends the value's JSON encoding to 'dst' and returns the extended slice.
+	AppendJSON(dst []byte) ([]byte, error)
+}
+
+// JSONMarshalerTo is a value that can write its own JSON encoding to a stream. When you respond
+// w/ one (or w/ anything that contains one), we use it directly rather than going through the
+// factory's Encoder.
+type JSONMarshalerTo interface {
+	// MarshalJSONTo writes the value's JSON encoding to the writer.
+	MarshalJSONTo(w io.Writer) error
+}
+
+// WithEncoder makes the factory's responders marshal JSON using your encoder rather than encoding/json.
+//
+//	responses := respond.NewFactory(
+//	    respond.WithEncoder(respond.EncoderFunc(sonic.Marshal)),
+//	)
+func WithEncoder(encoder Encoder) FactoryOption {
+	return func(factory *Factory) {
+		factory.encoder = encoder
+	}
+}
+
+// marshal encodes the value as JSON, using the value's own fast path (JSONAppender or
+// JSONMarshalerTo) if it has one or the factory's encoder otherwise. Values w/ "respond" tags or
+// nil collections that should be empty are walked by a jsonWriter, which does the same for each
+// of the values nested inside. It also indicates whether or not the result is compact JSON, which
+// only encoding/json guarantees.
+func (r Responder) marshal(value interface{}) ([]byte, bool, error) {
+	writer := r.jsonWriter()
+	jsonBytes, err := writer.append(nil, reflect.ValueOf(value), writer.emptyCollections)
+	return jsonBytes, writer.compact, err
+}
+
+// compactJSON removes insignificant whitespace from the JSON, so we can safely prune it.
+func compactJSON(jsonBytes []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(jsonBytes)))
+	if err := json.Compact(buf, jsonBytes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,233 @@
+package respond_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/monadicstack/respond"
+)
+
+type appendUser struct {
+	ID   int
+	Name string
+}
+
+// AppendJSON writes the user w/o any reflection.
+func (user appendUser) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, `{"id":`...)
+	dst = strconv.AppendInt(dst, int64(user.ID), 10)
+	dst = append(dst, `,"name":`...)
+	dst = strconv.AppendQuote(dst, user.Name)
+	return append(dst, `,"fast":true}`...), nil
+}
+
+type writerUser struct {
+	ID int
+}
+
+// MarshalJSONTo writes the user w/ some extra whitespace, like json.Encoder would.
+func (user writerUser) MarshalJSONTo(w io.Writer) error {
+	_, err := io.WriteString(w, `{ "id": `+strconv.Itoa(user.ID)+`, "stream": true }`+"\n")
+	return err
+}
+
+type failingUser struct{}
+
+func (failingUser) AppendJSON(dst []byte) ([]byte, error) {
+	return dst, errors.New("nope")
+}
+
+// appendMoney is a fast-path type whose JSON looks nothing like what encoding/json would do w/ it.
+type appendMoney struct {
+	Cents int
+}
+
+func (money appendMoney) AppendJSON(dst []byte) ([]byte, error) {
+	return append(dst, fmt.Sprintf(`"%d.%02d"`, money.Cents/100, money.Cents%100)...), nil
+}
+
+type appendInvoice struct {
+	Total appendMoney   `json:"total"`
+	Lines []appendMoney `json:"lines"`
+	Tip   *appendMoney  `json:"tip"`
+}
+
+// newIndentEncoder creates an encoder that pretty-prints JSON and records the values it marshals.
+func newIndentEncoder(values *[]interface{}) respond.Encoder {
+	return respond.EncoderFunc(func(value interface{}) ([]byte, error) {
+		*values = append(*values, value)
+		return json.MarshalIndent(value, "", "  ")
+	})
+}
+
+func (suite RespondSuite) TestEncoder_custom() {
+	var values []interface{}
+	factory := respond.NewFactory(respond.WithEncoder(newIndentEncoder(&values)))
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok(mockUser{ID: 1, Name: "Bob"})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/json")
+	suite.assertHeader(w, "Content-Length", "30")
+	suite.assertBody(w, "{\n  \"id\": 1,\n  \"name\": \"Bob\"\n}")
+	suite.Equal([]interface{}{mockUser{ID: 1, Name: "Bob"}}, values)
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).NotFound("user not found")
+	suite.assertStatus(w, 404)
+	suite.assertBody(w, "{\n  \"status\": 404,\n  \"message\": \"user not found\"\n}")
+}
+
+func (suite RespondSuite) TestEncoder_error() {
+	factory := respond.NewFactory(respond.WithEncoder(respond.EncoderFunc(func(value interface{}) ([]byte, error) {
+		return nil, errors.New("encoder broke")
+	})))
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok(mockUser{ID: 1})
+	suite.assertStatus(w, 500)
+	suite.assertBody(w, "json marshal error: encoder broke\n")
+}
+
+// Values w/ their own fast path should skip the encoder entirely.
+func (suite RespondSuite) TestEncoder_fastPaths() {
+	var values []interface{}
+	factory := respond.NewFactory(respond.WithEncoder(newIndentEncoder(&values)))
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok(appendUser{ID: 1, Name: "Bob"})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"id":1,"name":"Bob","fast":true}`)
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Created(writerUser{ID: 2})
+	suite.assertStatus(w, 201)
+	suite.assertBody(w, `{ "id": 2, "stream": true }`+"\n")
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(appendUser{ID: 3})
+	suite.assertBody(w, `{"id":3,"name":"","fast":true}`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(failingUser{})
+	suite.assertStatus(w, 500)
+	suite.assertBody(w, "json marshal error: nope\n")
+
+	suite.Empty(values)
+}
+
+// Fast-path values should be encoded the same way no matter where they're nested, even inside
+// plain structs/slices that encoding/json would otherwise handle for us.
+func (suite RespondSuite) TestEncoder_nestedFastPaths() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Ok(appendMoney{Cents: 1250})
+	suite.assertBody(w, `"12.50"`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok([]appendMoney{{Cents: 1250}, {Cents: 5}})
+	suite.assertBody(w, `["12.50","0.05"]`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(appendInvoice{Total: appendMoney{Cents: 1250}, Lines: []appendMoney{{Cents: 1000}, {Cents: 250}}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"total":"12.50","lines":["10.00","2.50"],"tip":null}`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(map[string]interface{}{"total": appendMoney{Cents: 99}, "users": []appendUser{{ID: 1}}})
+	suite.assertBody(w, `{"total":"0.99","users":[{"id":1,"name":"","fast":true}]}`)
+
+	// The encoder should still see everything else, but never the fast-path values.
+	var values []interface{}
+	factory := respond.NewFactory(respond.WithEncoder(newIndentEncoder(&values)))
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Ok(appendInvoice{Total: appendMoney{Cents: 1250}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"total":"12.50","lines":null,"tip":null}`)
+	for _, value := range values {
+		suite.NotEqual(appendMoney{Cents: 1250}, value)
+	}
+}
+
+// Sparse fieldsets and envelopes should work w/ any encoder, even one that doesn't produce compact JSON.
+func (suite RespondSuite) TestEncoder_features() {
+	var values []interface{}
+	factory := respond.NewFactory(
+		respond.WithEncoder(newIndentEncoder(&values)),
+		respond.WithSparseFields(""),
+	)
+
+	w := newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/users/1?fields=name", nil)).Ok(mockUser{ID: 1, Name: "Bob"})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"name":"Bob"}`)
+
+	w = newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/users/1?fields=stream", nil)).Ok(writerUser{ID: 1})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"stream":true}`)
+
+	values = nil
+	factory = respond.NewFactory(
+		respond.WithEncoder(newIndentEncoder(&values)),
+		respond.WithEnvelope(respond.EnvelopeOptions{}),
+	)
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Ok(mockUser{ID: 1, Name: "Bob"})
+	suite.assertStatus(w, 200)
+	suite.Contains(values, interface{}(mockUser{ID: 1, Name: "Bob"}))
+	var body map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(w.Body, &body))
+	suite.Equal(map[string]interface{}{"id": 1.0, "name": "Bob"}, body["data"])
+	suite.Nil(body["error"])
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Ok(appendUser{ID: 1, Name: "Bob"})
+	suite.Require().NoError(json.Unmarshal(w.Body, &body))
+	suite.Equal(map[string]interface{}{"id": 1.0, "name": "Bob", "fast": true}, body["data"])
+}
+
+type encoderTeam struct {
+	Name  string   `json:"name"`
+	Owner mockUser `json:"owner"`
+	Notes string   `json:"notes" respond:"view=admin"`
+}
+
+// The encoder should marshal the values nested inside of the ones we walk ourselves, not just the top-level value.
+func (suite RespondSuite) TestEncoder_nested() {
+	var values []interface{}
+	factory := respond.NewFactory(respond.WithEncoder(newIndentEncoder(&values)))
+	owner := mockUser{ID: 1, Name: "Bob"}
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok(encoderTeam{Name: "Cats", Owner: owner, Notes: "secret"})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "{\"name\":\"Cats\",\"owner\":{\n  \"id\": 1,\n  \"name\": \"Bob\"\n}}")
+	suite.Contains(values, interface{}(owner))
+
+	values = nil
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Ok(respond.HAL{
+		Value:    owner,
+		Links:    map[string]respond.HALLink{"self": {Href: "/users/1"}},
+		Embedded: map[string]interface{}{"items": []halItem{{SKU: "a"}}},
+	})
+	suite.assertStatus(w, 200)
+	suite.Contains(values, interface{}(owner))
+	var body map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(w.Body, &body))
+	suite.Equal("Bob", body["name"])
+	suite.Equal(map[string]interface{}{"items": []interface{}{map[string]interface{}{"sku": "a"}}}, body["_embedded"])
+
+	values = nil
+	factory = respond.NewFactory(respond.WithEncoder(newIndentEncoder(&values)), respond.WithJSONAPI())
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Ok(apiArticle{ID: "1", Title: "Hello", Tags: []string{"a", "b"}})
+	suite.assertStatus(w, 200)
+	suite.Contains(values, interface{}([]string{"a", "b"}))
+	suite.Require().NoError(json.Unmarshal(w.Body, &body))
+}
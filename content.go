@@ -0,0 +1,136 @@
+package respond
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// contentInfo contains the metadata we were able to figure out about some raw content we're
+// about to write. A size of -1 means that we don't know how big the content is.
+type contentInfo struct {
+	size    int64
+	modTime time.Time
+}
+
+// contentStatter is implemented by *os.File, fs.File, and anything else that can describe itself.
+type contentStatter interface {
+	Stat() (fs.FileInfo, error)
+}
+
+// contentLener is implemented by *bytes.Reader, *bytes.Buffer, *strings.Reader and friends
+// to indicate how many unread bytes are left.
+type contentLener interface {
+	Len() int
+}
+
+// statContent looks at each of the sources (typically the value/reader you're responding with) to
+// figure out the size and modification time of the raw content. The explicit ContentLengthReader and
+// ContentModTimeReader interfaces win, but we'll also look for Stat() like *os.File and fs.File have
+// (only trusting its size when we can seek to find out how much is left), or Len() like *bytes.Reader
+// has. Sources earlier in the list take precedence over later ones.
+func statContent(sources ...interface{}) contentInfo {
+	info := contentInfo{size: -1}
+	for _, source := range sources {
+		if v, ok := source.(ContentLengthReader); ok && info.size < 0 {
+			info.size = v.ContentLength()
+		}
+		if v, ok := source.(ContentModTimeReader); ok && info.modTime.IsZero() {
+			info.modTime = v.ContentModTime()
+		}
+		if v, ok := source.(contentStatter); ok && (info.size < 0 || info.modTime.IsZero()) {
+			if stat, err := v.Stat(); err == nil && stat.Mode().IsRegular() {
+				if info.size < 0 {
+					info.size = unreadSize(source, stat.Size())
+				}
+				if info.modTime.IsZero() {
+					info.modTime = stat.ModTime()
+				}
+			}
+		}
+		if v, ok := source.(contentLener); ok && info.size < 0 {
+			info.size = int64(v.Len())
+		}
+	}
+	if info.size < 0 {
+		info.size = -1
+	}
+	return info
+}
+
+// unreadSize determines how many bytes are left to read from a source whose Stat() says it's 'size'
+// bytes in total. The caller may have already read some of it, so we subtract the current offset
+// for seekable sources. We can't tell how much of a non-seekable stream is left, so it's -1.
+func unreadSize(source interface{}, size int64) int64 {
+	seeker, ok := source.(io.Seeker)
+	if !ok {
+		return -1
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil || offset > size {
+		return -1
+	}
+	return size - offset
+}
+
+// writeContent writes the status, size/modification headers, and the body for some raw content. The
+// Content-Type and Content-Disposition headers should already be set. For 200 responses we also honor
+// conditional requests (If-Modified-Since, etc.), and when the data is seekable, Range requests, too.
+// For HEAD requests, we write all of the same headers but never read/copy the data. The value is
+// what we report to the factory's write hooks (e.g. the ContentReader that supplied the data).
+func (r Responder) writeContent(status int, value interface{}, info contentInfo, data io.Reader) error {
+	if data == nil {
+		r.writeStatus(status)
+		return nil
+	}
+
+	if !info.modTime.IsZero() {
+		r.writer.Header().Set("Last-Modified", info.modTime.UTC().Format(http.TimeFormat))
+	}
+	return r.write(status, value, func(w http.ResponseWriter) error {
+		if status == http.StatusOK && r.request != nil {
+			// Let the standard library deal with ranges and conditional requests as long as we're at the
+			// start of the stream; ServeContent() always serves from the beginning of the data.
+			if seeker, ok := data.(io.ReadSeeker); ok {
+				if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil && offset == 0 {
+					http.ServeContent(w, r.request, "", info.modTime, seeker)
+					return nil
+				}
+			}
+			if notModifiedSince(r.request, info.modTime) {
+				w.Header().Del("Content-Type")
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+		}
+
+		if info.size >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.size, 10))
+		}
+		w.WriteHeader(status)
+		if r.isHead() {
+			return nil
+		}
+		_, err := io.Copy(w, data)
+		return err
+	})
+}
+
+// notModifiedSince determines whether the request's "If-Modified-Since" header indicates that the
+// caller already has the latest version of the content.
+func notModifiedSince(req *http.Request, modTime time.Time) bool {
+	if modTime.IsZero() || (req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return false
+	}
+	if req.Header.Get("If-None-Match") != "" {
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,223 @@
+package respond_test
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+var contentModTime = time.Date(2020, 5, 12, 8, 30, 15, 0, time.UTC)
+
+func (suite RespondSuite) TestServe_contentLength() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).ServeBytes("foo.txt", []byte("hello world"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "11")
+	suite.assertHeader(w, "Last-Modified", "")
+	suite.assertBody(w, "hello world")
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Download("foo.txt", strings.NewReader("hello"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "5")
+	suite.assertBody(w, "hello")
+}
+
+func (suite RespondSuite) TestServe_osFile() {
+	fileName := filepath.Join(suite.T().TempDir(), "hello.txt")
+	suite.Require().NoError(os.WriteFile(fileName, []byte("hello world"), 0600))
+	suite.Require().NoError(os.Chtimes(fileName, contentModTime, contentModTime))
+
+	file, err := os.Open(fileName)
+	suite.Require().NoError(err)
+	defer file.Close()
+
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Serve("hello.txt", file)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertHeader(w, "Content-Length", "11")
+	suite.assertHeader(w, "Last-Modified", "Tue, 12 May 2020 08:30:15 GMT")
+	suite.assertBody(w, "hello world")
+}
+
+func (suite RespondSuite) TestServe_fsFile() {
+	fsys := fstest.MapFS{
+		"hello.txt": &fstest.MapFile{Data: []byte("hello world"), ModTime: contentModTime},
+	}
+	file, err := fsys.Open("hello.txt")
+	suite.Require().NoError(err)
+	defer file.Close()
+
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Download("hello.txt", file)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "11")
+	suite.assertHeader(w, "Last-Modified", "Tue, 12 May 2020 08:30:15 GMT")
+	suite.assertBody(w, "hello world")
+}
+
+// Files that have already been partially read should only declare the bytes that are left.
+func (suite RespondSuite) TestServe_partiallyRead() {
+	fileName := filepath.Join(suite.T().TempDir(), "hello.txt")
+	suite.Require().NoError(os.WriteFile(fileName, []byte("hello world"), 0600))
+
+	file, err := os.Open(fileName)
+	suite.Require().NoError(err)
+	defer file.Close()
+	_, err = io.ReadFull(file, make([]byte, 6))
+	suite.Require().NoError(err)
+
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Download("hello.txt", file)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "5")
+	suite.assertBody(w, "world")
+
+	// We can't tell how much of a non-seekable file is left, so don't claim a length at all.
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(statOnlyContent{data: strings.NewReader("rld"), size: 11})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "")
+	suite.assertBody(w, "rld")
+}
+
+// Files from a file system are freshly opened, so their size is exact even when they can't seek.
+func (suite RespondSuite) TestServe_fsNotSeekable() {
+	fsys := statOnlyFS{"hello.txt": "hello world"}
+
+	w := newResponseWriter()
+	respond.FileServer(fsys, respond.FileServerOptions{}).ServeHTTP(w, newHTTPRequest(http.MethodGet, "/hello.txt"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "11")
+	suite.assertBody(w, "hello world")
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).ServeFile(fsys, "hello.txt")
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "11")
+}
+
+// statOnlyFS is a file system whose files can describe themselves w/ Stat() but can't seek.
+type statOnlyFS map[string]string
+
+func (fsys statOnlyFS) Open(name string) (fs.File, error) {
+	data, ok := fsys[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return statOnlyFile{reader: strings.NewReader(data), size: int64(len(data))}, nil
+}
+
+// statOnlyContent is a ContentReader whose data can describe itself w/ Stat() but can't seek.
+type statOnlyContent struct {
+	data io.Reader
+	size int64
+}
+
+func (c statOnlyContent) Content() io.ReadCloser {
+	return statOnlyFile{reader: c.data, size: c.size}
+}
+
+type statOnlyFile struct {
+	reader io.Reader
+	size   int64
+}
+
+func (f statOnlyFile) Read(p []byte) (int, error) { return f.reader.Read(p) }
+func (f statOnlyFile) Close() error               { return nil }
+func (f statOnlyFile) Stat() (fs.FileInfo, error) {
+	return fstest.MapFS{"f": &fstest.MapFile{Data: make([]byte, f.size)}}.Stat("f")
+}
+
+func (suite RespondSuite) TestServe_notModified() {
+	fsys := fstest.MapFS{
+		"hello.txt": &fstest.MapFile{Data: []byte("hello world"), ModTime: contentModTime},
+	}
+
+	file, _ := fsys.Open("hello.txt")
+	req := newHTTPRequest(http.MethodGet, "/hello.txt", "If-Modified-Since", "Tue, 12 May 2020 08:30:15 GMT")
+	w := newResponseWriter()
+	respond.To(w, req).Serve("hello.txt", file)
+	suite.assertStatus(w, 304)
+	suite.assertEmptyBody(w)
+
+	file, _ = fsys.Open("hello.txt")
+	req = newHTTPRequest(http.MethodGet, "/hello.txt", "If-Modified-Since", "Mon, 11 May 2020 08:30:15 GMT")
+	w = newResponseWriter()
+	respond.To(w, req).Serve("hello.txt", file)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "hello world")
+}
+
+func (suite RespondSuite) TestServe_range() {
+	req := newHTTPRequest(http.MethodGet, "/hello.txt", "Range", "bytes=6-")
+
+	w := newResponseWriter()
+	respond.To(w, req).ServeBytes("hello.txt", []byte("hello world"))
+	suite.assertStatus(w, 206)
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertHeader(w, "Content-Range", "bytes 6-10/11")
+	suite.assertHeader(w, "Content-Length", "5")
+	suite.assertBody(w, "world")
+}
+
+// Seekable data that we sniffed should still be served in full.
+func (suite RespondSuite) TestServe_rangeSniffed() {
+	req := newHTTPRequest(http.MethodGet, "/hello", "Range", "bytes=0-4")
+
+	w := newResponseWriter()
+	respond.To(w, req).Serve("hello", strings.NewReader("hello world"))
+	suite.assertStatus(w, 206)
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertBody(w, "hello")
+}
+
+func (suite RespondSuite) TestRaw_contentLengthAndModTime() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Ok(sizedContent{data: "hello world"})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "11")
+	suite.assertHeader(w, "Last-Modified", "Tue, 12 May 2020 08:30:15 GMT")
+	suite.assertRaw(w, "hello world")
+
+	req := newHTTPRequest(http.MethodGet, "/hello.txt", "If-Modified-Since", "Wed, 13 May 2020 00:00:00 GMT")
+	w = newResponseWriter()
+	respond.To(w, req).Ok(sizedContent{data: "hello world"})
+	suite.assertStatus(w, 304)
+	suite.assertEmptyBody(w)
+}
+
+// Conditional requests only make sense for 200 responses.
+func (suite RespondSuite) TestRaw_conditionalNotOk() {
+	req := newHTTPRequest(http.MethodGet, "/hello.txt", "If-Modified-Since", "Wed, 13 May 2020 00:00:00 GMT")
+
+	w := newResponseWriter()
+	respond.To(w, req).Created(sizedContent{data: "hello world"})
+	suite.assertStatus(w, 201)
+	suite.assertHeader(w, "Content-Length", "11")
+	suite.assertRaw(w, "hello world")
+}
+
+type sizedContent struct {
+	data string
+}
+
+func (c sizedContent) Content() io.ReadCloser {
+	return io.NopCloser(strings.NewReader(c.data))
+}
+
+func (c sizedContent) ContentLength() int64 {
+	return int64(len(c.data))
+}
+
+func (c sizedContent) ContentModTime() time.Time {
+	return contentModTime
+}
@@ -0,0 +1,272 @@
+package respond
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions customizes which cross-origin requests the CORS() middleware allows.
+type CORSOptions struct {
+	// AllowedOrigins are the origins that can make cross-origin requests. These can be exact
+	// origins ("https://app.example.com"), wildcard subdomains ("https://*.example.com"), or "*"
+	// to allow any origin.
+	AllowedOrigins []string
+	// AllowOriginFunc is an optional predicate for origins that aren't in AllowedOrigins. Return
+	// true to allow the origin to make the request.
+	AllowOriginFunc func(origin string, req *http.Request) bool
+	// AllowedMethods are the methods that cross-origin requests can use. When this is empty,
+	// we allow GET, HEAD, POST, PUT, PATCH, and DELETE.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers that cross-origin requests can include. When this
+	// is empty, we allow Accept, Accept-Language, Authorization, Content-Language, and Content-Type.
+	// Use "*" to allow any headers that the caller asks for.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers that the caller's JavaScript can read. When this is
+	// empty, we expose the headers that 'respond' itself sets, such as Location, ETag, and Retry-After.
+	ExposedHeaders []string
+	// AllowCredentials lets cross-origin requests include cookies and HTTP authentication.
+	AllowCredentials bool
+	// TrustForwardedProto uses the "X-Forwarded-Proto" header to determine whether the request came in
+	// over "http" or "https" when deciding if it's same-origin. Only enable this when you're behind a
+	// proxy that sets (or strips) the header; otherwise, clients could claim whatever scheme they like.
+	TrustForwardedProto bool
+	// MaxAge is how long the caller can cache the results of a preflight request. When this
+	// is zero, we don't send the header and the caller uses its own default.
+	MaxAge time.Duration
+	// Factory is the responder factory used to reject disallowed requests. When this is nil, we
+	// use the same default responders as the package-level To() function.
+	Factory *Factory
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+var defaultCORSHeaders = []string{
+	"Accept",
+	"Accept-Language",
+	"Authorization",
+	"Content-Language",
+	"Content-Type",
+}
+
+// defaultCORSExposedHeaders are the response headers that 'respond' sets that you'd typically
+// want cross-origin JavaScript to be able to see.
+var defaultCORSExposedHeaders = []string{
+	"Content-Disposition",
+	"Content-Length",
+	"ETag",
+	"Last-Modified",
+	"Link",
+	"Location",
+	"Retry-After",
+	"X-Total-Count",
+}
+
+// CORS creates middleware that handles Cross-Origin Resource Sharing using only the standard
+// library. Preflight (OPTIONS) requests are answered by the middleware, and the appropriate
+// "Access-Control-*" headers are added to all other cross-origin requests. Requests from origins
+// you don't allow are rejected using Fail(), so they look like every other error from your API.
+//
+//	handler = respond.CORS(respond.CORSOptions{
+//	    AllowedOrigins: []string{"https://app.example.com", "https://*.example.com"},
+//	    MaxAge:         time.Hour,
+//	})(handler)
+func CORS(options CORSOptions) func(http.Handler) http.Handler {
+	if len(options.AllowedMethods) == 0 {
+		options.AllowedMethods = defaultCORSMethods
+	}
+	if len(options.AllowedHeaders) == 0 {
+		options.AllowedHeaders = defaultCORSHeaders
+	}
+	if len(options.ExposedHeaders) == 0 {
+		options.ExposedHeaders = defaultCORSExposedHeaders
+	}
+	if options.Factory == nil {
+		options.Factory = defaultFactory
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			options.serveHTTP(next, w, req)
+		})
+	}
+}
+
+// serveHTTP applies the CORS rules to the request before (maybe) handing it off to the next handler.
+func (options CORSOptions) serveHTTP(next http.Handler, w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if !options.allowsAnyOrigin() || options.AllowCredentials {
+		addVary(w.Header(), "Origin")
+	}
+
+	// Not a cross-origin request, so the browser doesn't need any special headers.
+	if origin == "" || isSameOrigin(origin, req, options.TrustForwardedProto) {
+		next.ServeHTTP(w, req)
+		return
+	}
+
+	response := options.Factory.To(w, req)
+	if !options.allowsOrigin(origin, req) {
+		response.Forbidden("cross-origin request not allowed from origin: %s", origin)
+		return
+	}
+
+	preflightMethod := req.Header.Get("Access-Control-Request-Method")
+	if req.Method != http.MethodOptions || preflightMethod == "" {
+		options.writeOriginHeaders(w.Header(), origin)
+		if len(options.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(options.ExposedHeaders, ", "))
+		}
+		next.ServeHTTP(w, req)
+		return
+	}
+
+	addVary(w.Header(), "Access-Control-Request-Method")
+	addVary(w.Header(), "Access-Control-Request-Headers")
+	if !containsFold(options.AllowedMethods, preflightMethod) {
+		response.Forbidden("cross-origin request not allowed for method: %s", preflightMethod)
+		return
+	}
+	requestedHeaders := parseHeaderList(req.Header.Get("Access-Control-Request-Headers"))
+	for _, header := range requestedHeaders {
+		if !options.allowsHeader(header) {
+			response.Forbidden("cross-origin request not allowed for header: %s", header)
+			return
+		}
+	}
+
+	options.writeOriginHeaders(w.Header(), origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(options.AllowedMethods, ", "))
+	if len(requestedHeaders) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+	}
+	if options.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge.Seconds())))
+	}
+	response.NoContent()
+}
+
+// writeOriginHeaders applies the "Access-Control-Allow-Origin" and "Access-Control-Allow-Credentials"
+// headers that both preflight and actual requests need.
+func (options CORSOptions) writeOriginHeaders(header http.Header, origin string) {
+	// Browsers don't allow "*" for credentialed requests, so we need to echo the origin instead.
+	if options.allowsAnyOrigin() && !options.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if options.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// allowsAnyOrigin determines if the options include the "*" wildcard origin.
+func (options CORSOptions) allowsAnyOrigin() bool {
+	for _, allowed := range options.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsOrigin determines if the given origin is allowed to make cross-origin requests.
+func (options CORSOptions) allowsOrigin(origin string, req *http.Request) bool {
+	for _, allowed := range options.AllowedOrigins {
+		if matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return options.AllowOriginFunc != nil && options.AllowOriginFunc(origin, req)
+}
+
+// allowsHeader determines if cross-origin requests can include the given request header.
+func (options CORSOptions) allowsHeader(header string) bool {
+	return containsFold(options.AllowedHeaders, "*") || containsFold(options.AllowedHeaders, header)
+}
+
+// matchOrigin compares the origin against an allowed origin pattern such as "*",
+// "https://app.example.com", or "https://*.example.com", ignoring case.
+func matchOrigin(pattern string, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	pattern = strings.ToLower(pattern)
+	origin = strings.ToLower(origin)
+	wildcard := strings.Index(pattern, "*")
+	if wildcard < 0 {
+		return pattern == origin
+	}
+
+	prefix, suffix := pattern[:wildcard], pattern[wildcard+1:]
+	if len(origin) <= len(prefix)+len(suffix) {
+		return false
+	}
+	subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+	return strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) && !strings.ContainsAny(subdomain, "/:")
+}
+
+// isSameOrigin determines if the "Origin" header points at the same scheme and host that received the
+// request, which browsers send along w/ same-origin POSTs and such. An "http://" page calling the "https://"
+// version of the same host is still cross-origin as far as the browser is concerned.
+func isSameOrigin(origin string, req *http.Request, trustForwarded bool) bool {
+	schemeEnd := strings.Index(origin, "://")
+	return schemeEnd >= 0 &&
+		strings.EqualFold(origin[:schemeEnd], requestScheme(req, trustForwarded)) &&
+		strings.EqualFold(origin[schemeEnd+3:], req.Host)
+}
+
+// requestScheme determines whether the caller used "http" or "https" to make the request. When we're
+// behind a trusted proxy that terminates TLS, we rely on it to tell us via the "X-Forwarded-Proto" header.
+func requestScheme(req *http.Request, trustForwarded bool) string {
+	if req.TLS != nil {
+		return "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); trustForwarded && proto != "" {
+		return strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+	return "http"
+}
+
+// parseHeaderList splits a comma-separated header value like "Content-Type, X-Foo" into
+// its individual (canonicalized) header names.
+func parseHeaderList(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return headers
+}
+
+// addVary adds the value to the "Vary" header unless it's already there.
+func addVary(header http.Header, value string) {
+	for _, existing := range header.Values("Vary") {
+		for _, field := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
+
+// containsFold determines if the list contains the value, ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package respond_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+func newCORSHandler(options respond.CORSOptions) http.Handler {
+	return respond.CORS(options)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		respond.To(w, req).Ok("hello")
+	}))
+}
+
+func newCORSRequest(method string, origin string) *http.Request {
+	req := httptest.NewRequest(method, "http://api.example.com/foo", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	return req
+}
+
+func newPreflightRequest(origin string, method string, headers string) *http.Request {
+	req := newCORSRequest(http.MethodOptions, origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return req
+}
+
+// Requests w/o an Origin (or from the same origin) aren't cross-origin, so they shouldn't get CORS headers.
+func (suite RespondSuite) TestCORS_notCrossOrigin() {
+	handler := newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}})
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, ""))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `"hello"`)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "")
+	suite.assertHeader(w, "Vary", "Origin")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodPost, "http://api.example.com"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "")
+
+	// Behind a trusted proxy that terminates TLS, the forwarded scheme is the real one.
+	handler = newCORSHandler(respond.CORSOptions{
+		AllowedOrigins:      []string{"https://app.example.com"},
+		TrustForwardedProto: true,
+	})
+	req := newCORSRequest(http.MethodPost, "https://api.example.com")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = newResponseWriter()
+	handler.ServeHTTP(w, req)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "")
+}
+
+// The same host over a different scheme is a different origin, so it needs to pass the normal checks.
+func (suite RespondSuite) TestCORS_sameHostDifferentScheme() {
+	handler := newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"https://api.example.com"}})
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodPost, "https://api.example.com"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "https://api.example.com")
+
+	// Clients can send whatever "X-Forwarded-Proto" they like, so we ignore it unless told otherwise.
+	req := newCORSRequest(http.MethodPost, "http://api.example.com")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = newResponseWriter()
+	handler.ServeHTTP(w, req)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "")
+
+	handler = newCORSHandler(respond.CORSOptions{
+		AllowedOrigins:      []string{"https://api.example.com"},
+		TrustForwardedProto: true,
+	})
+	w = newResponseWriter()
+	handler.ServeHTTP(w, req)
+	suite.assertStatus(w, 403)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodPost, "https://api.example.com"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "https://api.example.com")
+}
+
+func (suite RespondSuite) TestCORS_exactOrigin() {
+	handler := newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}})
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, "https://APP.example.com"))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `"hello"`)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "https://APP.example.com")
+	suite.assertHeader(w, "Access-Control-Allow-Credentials", "")
+	suite.assertHeader(w, "Vary", "Origin")
+	suite.assertHeader(w, "Access-Control-Expose-Headers", "Content-Disposition, Content-Length, ETag, Last-Modified, Link, Location, Retry-After, X-Total-Count")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, "http://app.example.com"))
+	suite.assertError(w, 403, "cross-origin request not allowed from origin: http://app.example.com")
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "")
+	suite.assertHeader(w, "Vary", "Origin")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, "https://app.example.com.evil.com"))
+	suite.assertError(w, 403, "cross-origin request not allowed from origin: https://app.example.com.evil.com")
+}
+
+func (suite RespondSuite) TestCORS_wildcardSubdomain() {
+	handler := newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"https://*.example.com"}})
+	allowed := func(origin string) bool {
+		w := newResponseWriter()
+		handler.ServeHTTP(w, newCORSRequest(http.MethodGet, origin))
+		return w.StatusCode == 200 && w.Header().Get("Access-Control-Allow-Origin") == origin
+	}
+
+	suite.True(allowed("https://app.example.com"))
+	suite.True(allowed("https://a.b.example.com"))
+	suite.False(allowed("https://example.com"))
+	suite.False(allowed("http://app.example.com"))
+	suite.False(allowed("https://app.example.com:8443"))
+	suite.False(allowed("https://evilexample.com"))
+	suite.False(allowed("https://example.com.evil.com"))
+}
+
+func (suite RespondSuite) TestCORS_predicate() {
+	handler := newCORSHandler(respond.CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowOriginFunc: func(origin string, req *http.Request) bool {
+			return strings.HasSuffix(origin, ".trusted.io")
+		},
+	})
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, "https://app.example.com"))
+	suite.assertStatus(w, 200)
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, "https://foo.trusted.io"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "https://foo.trusted.io")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, "https://foo.untrusted.io"))
+	suite.assertError(w, 403, "cross-origin request not allowed from origin: https://foo.untrusted.io")
+}
+
+// A "*" origin doesn't depend on the caller's origin, so it shouldn't vary unless we need to echo the origin.
+func (suite RespondSuite) TestCORS_anyOrigin() {
+	w := newResponseWriter()
+	newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"*"}}).ServeHTTP(w, newCORSRequest(http.MethodGet, "https://foo.io"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "*")
+	suite.assertHeader(w, "Vary", "")
+
+	w = newResponseWriter()
+	newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}).ServeHTTP(w, newCORSRequest(http.MethodGet, "https://foo.io"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "https://foo.io")
+	suite.assertHeader(w, "Access-Control-Allow-Credentials", "true")
+	suite.assertHeader(w, "Vary", "Origin")
+}
+
+func (suite RespondSuite) TestCORS_preflight() {
+	handler := newCORSHandler(respond.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Content-Type", "X-Api-Key"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newPreflightRequest("https://app.example.com", http.MethodPost, "content-type, x-api-key"))
+	suite.assertStatus(w, 204)
+	suite.assertEmptyBody(w)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "https://app.example.com")
+	suite.assertHeader(w, "Access-Control-Allow-Credentials", "true")
+	suite.assertHeader(w, "Access-Control-Allow-Methods", "GET, POST")
+	suite.assertHeader(w, "Access-Control-Allow-Headers", "Content-Type, X-Api-Key")
+	suite.assertHeader(w, "Access-Control-Max-Age", "600")
+	suite.Equal([]string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newPreflightRequest("https://app.example.com", http.MethodDelete, ""))
+	suite.assertError(w, 403, "cross-origin request not allowed for method: DELETE")
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newPreflightRequest("https://app.example.com", http.MethodPost, "Content-Type, X-Secret"))
+	suite.assertError(w, 403, "cross-origin request not allowed for header: X-Secret")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newPreflightRequest("https://evil.com", http.MethodPost, ""))
+	suite.assertError(w, 403, "cross-origin request not allowed from origin: https://evil.com")
+}
+
+// OPTIONS requests that aren't preflights should make it to your handler like any other request.
+func (suite RespondSuite) TestCORS_optionsNotPreflight() {
+	w := newResponseWriter()
+	newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}).ServeHTTP(w, newCORSRequest(http.MethodOptions, "https://app.example.com"))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `"hello"`)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "https://app.example.com")
+}
+
+func (suite RespondSuite) TestCORS_anyHeader() {
+	handler := newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newPreflightRequest("https://foo.io", http.MethodPut, "X-Foo,X-Bar"))
+	suite.assertStatus(w, 204)
+	suite.assertHeader(w, "Access-Control-Allow-Origin", "*")
+	suite.assertHeader(w, "Access-Control-Allow-Headers", "X-Foo, X-Bar")
+	suite.assertHeader(w, "Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE")
+	suite.assertHeader(w, "Access-Control-Max-Age", "")
+}
+
+// Vary shouldn't end up w/ duplicate "Origin" values if someone upstream already added it.
+func (suite RespondSuite) TestCORS_varyNoDuplicates() {
+	w := newResponseWriter()
+	w.Header().Add("Vary", "Accept-Encoding, origin")
+	newCORSHandler(respond.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}).ServeHTTP(w, newCORSRequest(http.MethodGet, "https://app.example.com"))
+	suite.Equal([]string{"Accept-Encoding, origin"}, w.Header().Values("Vary"))
+}
+
+// Rejections should use the factory's responders (and therefore its error formatting).
+func (suite RespondSuite) TestCORS_factory() {
+	handler := respond.CORS(respond.CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		Factory:        respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{})),
+	})(http.NotFoundHandler())
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newCORSRequest(http.MethodGet, "https://evil.com"))
+	suite.assertError(w, 403, "cross-origin request not allowed from origin: https://evil.com")
+}
@@ -0,0 +1,114 @@
+package respond
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// attachmentDisposition builds an RFC 6266 "Content-Disposition" header value that prompts the
+// caller to download the content using the given file name. The name is sanitized first; we
+// strip any directories and control characters. We always include an ASCII-safe "filename"
+// parameter for older clients, and when the name contains non-ASCII characters we also include
+// an RFC 5987 "filename*" parameter with the UTF-8 version of the name that modern clients prefer.
+//
+//	attachmentDisposition("report.pdf")        // attachment; filename="report.pdf"
+//	attachmentDisposition("Résumé – 2024.pdf") // attachment; filename="Resume - 2024.pdf"; filename*=UTF-8''R%C3%A9sum%C3%A9%20%E2%80%93%202024.pdf
+func attachmentDisposition(fileName string) string {
+	fileName = sanitizeFileName(fileName)
+	if fileName == "" {
+		return "attachment"
+	}
+
+	fallback := asciiFileName(fileName)
+	disposition := `attachment; filename="` + quoteFileName(fallback) + `"`
+	if fallback == fileName {
+		return disposition
+	}
+	return disposition + "; filename*=UTF-8''" + encodeRFC5987(fileName)
+}
+
+// sanitizeFileName strips any directory info (both "/" and "\" separated) as well as control
+// characters from the file name, so all that's left is a name that's safe to suggest to the client.
+func sanitizeFileName(fileName string) string {
+	if sep := strings.LastIndexAny(fileName, `/\`); sep >= 0 {
+		fileName = fileName[sep+1:]
+	}
+	fileName = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, fileName)
+	return strings.TrimSpace(fileName)
+}
+
+// asciiFileName converts the file name to one that only contains printable ASCII characters.
+// Common accented Latin letters and punctuation are replaced w/ their closest ASCII equivalent
+// and anything else becomes an underscore.
+func asciiFileName(fileName string) string {
+	builder := strings.Builder{}
+	for _, r := range fileName {
+		switch {
+		case r >= 0x20 && r < 0x7F:
+			builder.WriteRune(r)
+		case asciiReplacements[r] != "":
+			builder.WriteString(asciiReplacements[r])
+		default:
+			builder.WriteByte('_')
+		}
+	}
+	return builder.String()
+}
+
+// quoteFileName escapes the file name so that it's a valid quoted-string in an HTTP header.
+func quoteFileName(fileName string) string {
+	fileName = strings.ReplaceAll(fileName, `\`, `\\`)
+	return strings.ReplaceAll(fileName, `"`, `\"`)
+}
+
+// encodeRFC5987 percent-encodes the UTF-8 bytes of the value, leaving only the "attr-char"
+// characters from RFC 5987 as-is.
+func encodeRFC5987(value string) string {
+	const hex = "0123456789ABCDEF"
+
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		b := value[i]
+		if isAttrChar(b) {
+			builder.WriteByte(b)
+			continue
+		}
+		builder.WriteByte('%')
+		builder.WriteByte(hex[b>>4])
+		builder.WriteByte(hex[b&0x0F])
+	}
+	return builder.String()
+}
+
+// isAttrChar determines if the byte can appear unencoded in an RFC 5987 ext-value.
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	default:
+		return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+	}
+}
+
+// asciiReplacements maps common non-ASCII characters to their closest ASCII equivalents when
+// building the fallback "filename" parameter.
+var asciiReplacements = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE", 'Ç': "C",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I",
+	'Ð': "D", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ý': "Y", 'Þ': "TH", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae", 'ç': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ð': "d", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'þ': "th", 'ÿ': "y",
+	'Œ': "OE", 'œ': "oe", 'Š': "S", 'š': "s", 'Ž': "Z", 'ž': "z", 'Ÿ': "Y",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-",
+	'‘': "'", '’': "'", '‚': "'", '“': "'", '”': "'", '„': "'",
+	'…': "...", '\u00A0': " ",
+}
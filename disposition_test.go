@@ -0,0 +1,72 @@
+package respond_test
+
+import (
+	"github.com/monadicstack/respond"
+)
+
+func (suite RespondSuite) TestDownload_dispositionUnicode() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).DownloadBytes("Résumé – 2024.pdf", []byte("hello"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Disposition",
+		`attachment; filename="Resume - 2024.pdf"; filename*=UTF-8''R%C3%A9sum%C3%A9%20%E2%80%93%202024.pdf`)
+}
+
+func (suite RespondSuite) TestDownload_dispositionUnmapped() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).DownloadBytes("日本.txt", []byte("hello"))
+	suite.assertHeader(w, "Content-Disposition",
+		`attachment; filename="__.txt"; filename*=UTF-8''%E6%97%A5%E6%9C%AC.txt`)
+}
+
+func (suite RespondSuite) TestDownload_dispositionEscaping() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).DownloadBytes(`the-"stranger".log`, []byte("hello"))
+	suite.assertHeader(w, "Content-Disposition", `attachment; filename="the-\"stranger\".log"`)
+}
+
+// Directories, control characters, and header injection attempts should be stripped.
+func (suite RespondSuite) TestDownload_dispositionSanitized() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).DownloadBytes("../../etc/pass\r\nX-Evil: 1\twd", []byte("hello"))
+	suite.assertHeader(w, "Content-Disposition", `attachment; filename="passX-Evil: 1wd"`)
+
+	w = newResponseWriter()
+	respond.To(w, req).DownloadBytes(`C:\Users\bob\report.csv`, []byte("hello"))
+	suite.assertHeader(w, "Content-Disposition", `attachment; filename="report.csv"`)
+}
+
+func (suite RespondSuite) TestDownload_dispositionEmpty() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).DownloadBytes("some/dir/", []byte("hello"))
+	suite.assertHeader(w, "Content-Disposition", "attachment")
+}
+
+// ContentFileNameReader values should get the same treatment as Download().
+func (suite RespondSuite) TestRaw_contentDispositionUnicode() {
+	type R struct {
+		rawContentReader
+		rawContentFileName
+	}
+
+	w := newResponseWriter()
+	req := newRequest()
+
+	result := R{}
+	result.reader = newRawString("")
+	result.fileName = "reports/Ünïcödé\x00.csv"
+
+	respond.To(w, req).Ok(result)
+	suite.assertHeader(w, "Content-Disposition",
+		`attachment; filename="Unicode.csv"; filename*=UTF-8''%C3%9Cn%C3%AFc%C3%B6d%C3%A9.csv`)
+}
@@ -0,0 +1,88 @@
+package respond
+
+import "reflect"
+
+// WithEmptyCollections makes the factory's responders marshal nil slices and maps as [] and {}
+// rather than null, no matter how deeply they're nested in your values. That way, clients can
+// always check the length of a list w/o worrying about whether it's null first. You can override
+// this for individual struct fields using tags:
+//
+//	Tags []string          `json:"tags" respond:"empty"`       // Always [] when nil, even w/o this option.
+//	Meta map[string]string `json:"meta" respond:"nullable"`    // Always null when nil, even w/ this option.
+func WithEmptyCollections() FactoryOption {
+	return func(factory *Factory) {
+		factory.emptyCollections = true
+	}
+}
+
+// emptyMode is how a struct field w/ a nil slice/map should be marshaled.
+type emptyMode int
+
+const (
+	// emptyInherit uses the factory's WithEmptyCollections() setting.
+	emptyInherit emptyMode = iota
+	// emptyAlways marshals a nil slice/map as [] or {} (respond:"empty").
+	emptyAlways
+	// emptyNever marshals a nil slice/map as null (respond:"nullable").
+	emptyNever
+)
+
+// emptyCollections determines if a nil slice/map in this field should be marshaled as [] or {},
+// given the factory's setting.
+func (field structField) emptyCollections(factoryDefault bool) bool {
+	return field.empty.resolve(factoryDefault)
+}
+
+// resolve determines if a nil slice/map should be marshaled as [] or {}, given the factory's setting.
+func (mode emptyMode) resolve(factoryDefault bool) bool {
+	switch mode {
+	case emptyAlways:
+		return true
+	case emptyNever:
+		return false
+	default:
+		return factoryDefault
+	}
+}
+
+// isCollectionType determines if the type is a slice/map that encoding/json would marshal as null when
+// it's nil. Byte slices are marshaled as base64 strings rather than arrays, so they don't count.
+func isCollectionType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		return !hasCustomJSON(t)
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8 && !hasCustomJSON(t)
+	default:
+		return false
+	}
+}
+
+// hasNestedCollections determines if a value of this type can contain a slice or map (other than itself)
+// that might be nil, such as a struct field, a slice of slices, or a pointer to a map. It keeps track of
+// the types we've already visited so that recursive types (e.g. a tree of nodes) don't recurse forever.
+// Interfaces don't count since we can't know what they hold until we have the value.
+func hasNestedCollections(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	if hasCustomJSON(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+		return isCollectionType(t.Elem()) || hasNestedCollections(t.Elem(), visited)
+	case reflect.Struct:
+		for _, field := range structFields(t).fields {
+			if isCollectionType(field.typ) || hasNestedCollections(field.typ, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
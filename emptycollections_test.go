@@ -0,0 +1,132 @@
+package respond_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/monadicstack/respond"
+)
+
+type emptyTeam struct {
+	Name     string            `json:"name"`
+	Members  []mockUser        `json:"members"`
+	Labels   map[string]string `json:"labels"`
+	Scores   [][]int           `json:"scores"`
+	Lead     *emptyTeam        `json:"lead,omitempty"`
+	Avatar   []byte            `json:"avatar"`
+	Raw      json.RawMessage   `json:"raw"`
+	Optional []string          `json:"optional,omitempty"`
+	Nullable []string          `json:"nullable" respond:"nullable"`
+	Extra    interface{}       `json:"extra"`
+}
+
+type emptyTagged struct {
+	Tags  []string          `json:"tags" respond:"empty"`
+	Meta  map[string]string `json:"meta" respond:"empty"`
+	Other []string          `json:"other"`
+}
+
+func (suite RespondSuite) respondEmpty(value interface{}) *mockResponseWriter {
+	w := newResponseWriter()
+	respond.NewFactory(respond.WithEmptyCollections()).To(w, newHTTPRequest(http.MethodGet, "/")).Ok(value)
+	return w
+}
+
+func (suite RespondSuite) TestEmptyCollections_topLevel() {
+	w := suite.respondEmpty([]mockUser(nil))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `[]`)
+
+	w = suite.respondEmpty(map[string]int(nil))
+	suite.assertBody(w, `{}`)
+
+	w = suite.respondEmpty([]byte(nil))
+	suite.assertBody(w, `null`)
+
+	w = suite.respondEmpty((*emptyTeam)(nil))
+	suite.assertBody(w, `null`)
+
+	w = suite.respondEmpty([]int{1, 2})
+	suite.assertBody(w, `[1,2]`)
+}
+
+// Nil slices/maps should be normalized no matter how deeply they're nested.
+func (suite RespondSuite) TestEmptyCollections_nested() {
+	w := suite.respondEmpty(emptyTeam{
+		Name:   "Cats",
+		Scores: [][]int{nil, {1}},
+		Lead:   &emptyTeam{Name: "Bob"},
+		Extra:  map[string]interface{}{"list": []string(nil)},
+	})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"name":"Cats","members":[],"labels":{},"scores":[[],[1]],`+
+		`"lead":{"name":"Bob","members":[],"labels":{},"scores":[],"avatar":null,"raw":null,"nullable":null,"extra":null},`+
+		`"avatar":null,"raw":null,"nullable":null,"extra":{"list":[]}}`)
+
+	w = suite.respondEmpty(map[string][]mockUser{"a": nil, "b": {{ID: 1}}})
+	suite.assertBody(w, `{"a":[],"b":[{"id":1,"name":""}]}`)
+
+	w = suite.respondEmpty(respond.PageNumber([]mockUser(nil), 1, 10, 0))
+	suite.assertBody(w, `[]`)
+}
+
+// The "empty" tag should work even when the factory doesn't normalize everything.
+func (suite RespondSuite) TestEmptyCollections_tags() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Ok(emptyTagged{})
+	suite.assertBody(w, `{"tags":[],"meta":{},"other":null}`)
+
+	w = suite.respondEmpty(emptyTagged{})
+	suite.assertBody(w, `{"tags":[],"meta":{},"other":[]}`)
+}
+
+type emptyPointers struct {
+	Tags     *[]string       `json:"tags" respond:"empty"`
+	Nullable *[]string       `json:"nullable" respond:"nullable"`
+	Any      interface{}     `json:"any" respond:"nullable"`
+	Meta     *map[string]int `json:"meta"`
+}
+
+// Tags should apply to the slice/map behind a pointer or interface, too.
+func (suite RespondSuite) TestEmptyCollections_pointers() {
+	var tags, nullable []string
+	var meta map[string]int
+	value := emptyPointers{Tags: &tags, Nullable: &nullable, Any: []string(nil), Meta: &meta}
+
+	w := suite.respondEmpty(value)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"tags":[],"nullable":null,"any":null,"meta":{}}`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(value)
+	suite.assertBody(w, `{"tags":[],"nullable":null,"any":null,"meta":null}`)
+}
+
+func (suite RespondSuite) TestEmptyCollections_disabled() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Ok(emptyTeam{Name: "Cats"})
+	suite.assertBody(w, `{"name":"Cats","members":null,"labels":null,"scores":null,"avatar":null,"raw":null,"nullable":null,"extra":null}`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok([]mockUser(nil))
+	suite.assertBody(w, `null`)
+}
+
+// Errors, envelopes, and other formats should be normalized, too.
+func (suite RespondSuite) TestEmptyCollections_formats() {
+	factory := respond.NewFactory(respond.WithEmptyCollections(), respond.WithEnvelope(respond.EnvelopeOptions{}))
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok([]mockUser(nil))
+	suite.assertBody(w, `{"data":[],"meta":{},"error":null}`)
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).NotFound("nope")
+	suite.assertBody(w, `{"data":null,"meta":{},"error":{"status":404,"message":"nope"}}`)
+
+	w = newResponseWriter()
+	respond.NewFactory(respond.WithEmptyCollections()).To(w, newHTTPRequest(http.MethodGet, "/orders/")).Ok(respond.HAL{
+		Value:    emptyTagged{},
+		Embedded: map[string]interface{}{"items": []mockUser(nil)},
+	})
+	suite.assertBody(w, `{"_embedded":{"items":[]},"tags":[],"meta":{},"other":[]}`)
+}
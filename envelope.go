@@ -0,0 +1,95 @@
+package respond
+
+import "net/http"
+
+// EnvelopeOptions customizes the envelope that the factory's responders wrap JSON responses in.
+type EnvelopeOptions struct {
+	// DataKey is the name of the envelope field w/ the value you responded with. When this
+	// is empty, we use "data".
+	DataKey string
+	// MetaKey is the name of the envelope field w/ the response's metadata. When this
+	// is empty, we use "meta".
+	MetaKey string
+	// ErrorKey is the name of the envelope field w/ the error details (status/message) when you
+	// Fail(). When this is empty, we use "error".
+	ErrorKey string
+	// Meta builds the metadata for each response, such as the request ID, timing info, or your API
+	// version. When this is nil (or returns nil), the meta is an empty object.
+	Meta func(event EnvelopeEvent) interface{}
+}
+
+// EnvelopeEvent describes the response that we're wrapping in an envelope so that you can build
+// its metadata.
+type EnvelopeEvent struct {
+	// Request is the HTTP request we're responding to. This may be nil if you created the responder
+	// w/o a request.
+	Request *http.Request
+	// Status is the HTTP status code of the response.
+	Status int
+	// Value is the value you responded with. It's nil when this is an error response.
+	Value interface{}
+	// Failure is the original error you failed with when this is an error response (i.e. what you
+	// passed to Fail()). It's nil for successful responses.
+	Failure error
+	// RequestID is the ID of the request we're responding to, if the factory or middleware supplied one.
+	RequestID string
+}
+
+// WithEnvelope wraps every JSON response from the factory's responders in an envelope, so your
+// callers always get the same structure whether the request succeeded or not.
+//
+//	{"data": {...}, "meta": {...}, "error": null}
+//	{"data": null, "meta": {...}, "error": {"status": 404, "message": "user not found"}}
+//
+// Raw content, redirects, HTML, and responses w/o a body are left alone, as are HAL and JSON:API
+// documents since they have their own structure.
+func WithEnvelope(options EnvelopeOptions) FactoryOption {
+	if options.DataKey == "" {
+		options.DataKey = "data"
+	}
+	if options.MetaKey == "" {
+		options.MetaKey = "meta"
+	}
+	if options.ErrorKey == "" {
+		options.ErrorKey = "error"
+	}
+	return func(factory *Factory) {
+		factory.envelope = &options
+	}
+}
+
+// envelope wraps the value in the factory's envelope. The sparse fieldset (if any) is updated to
+// apply to the data rather than the envelope itself.
+func (r Responder) envelope(status int, value interface{}, fields *sparseFields) (jsonObject, *sparseFields) {
+	options := r.factory.envelope
+	event := EnvelopeEvent{
+		Request:   r.request,
+		Status:    status,
+		Value:     value,
+		Failure:   r.failure,
+		RequestID: r.requestID,
+	}
+
+	var data, errorDetails interface{} = value, nil
+	if r.failure != nil {
+		event.Value = nil
+		data, errorDetails = nil, value
+	}
+
+	var meta interface{} = struct{}{}
+	if options.Meta != nil {
+		if eventMeta := options.Meta(event); eventMeta != nil {
+			meta = eventMeta
+		}
+	}
+
+	if fields != nil {
+		fields = fields.wrap(fieldTree{options.DataKey: fields.tree, options.MetaKey: fieldTree{}, options.ErrorKey: fieldTree{}})
+	}
+	body := jsonObject{
+		{name: options.DataKey, value: data},
+		{name: options.MetaKey, value: meta},
+		{name: options.ErrorKey, value: errorDetails},
+	}
+	return body, fields
+}
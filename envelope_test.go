@@ -0,0 +1,136 @@
+package respond_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/monadicstack/respond"
+)
+
+func newEnvelopeFactory(options ...respond.FactoryOption) *respond.Factory {
+	options = append(options, respond.WithEnvelope(respond.EnvelopeOptions{
+		Meta: func(event respond.EnvelopeEvent) interface{} {
+			return map[string]interface{}{"requestId": event.RequestID, "version": "v2"}
+		},
+	}))
+	return respond.NewFactory(options...)
+}
+
+func (suite RespondSuite) TestEnvelope_success() {
+	factory := newEnvelopeFactory(respond.WithRequestID(respond.RequestIDOptions{}))
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/users/1", "X-Request-ID", "abc123")).Ok(mockUser{ID: 1, Name: "Bob"})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/json")
+	suite.assertBody(w, `{"data":{"id":1,"name":"Bob"},"meta":{"requestId":"abc123","version":"v2"},"error":null}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/users", "X-Request-ID", "abc123")).Created(nil)
+	suite.assertStatus(w, 201)
+	suite.assertBody(w, `{"data":null,"meta":{"requestId":"abc123","version":"v2"},"error":null}`)
+}
+
+func (suite RespondSuite) TestEnvelope_fail() {
+	factory := newEnvelopeFactory(respond.WithRequestID(respond.RequestIDOptions{}))
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/users/1", "X-Request-ID", "abc123")).Ok(mockUser{ID: 1}, errorWithStatus{status: 404, message: "user not found"})
+	suite.assertStatus(w, 404)
+	suite.assertHeader(w, "Content-Type", "application/json")
+	suite.assertBody(w, `{"data":null,"meta":{"requestId":"abc123","version":"v2"},"error":{"status":404,"message":"user not found","requestId":"abc123"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/users/1", "X-Request-ID", "abc123")).InternalServerError("oops")
+	suite.assertStatus(w, 500)
+	suite.assertBody(w, `{"data":null,"meta":{"requestId":"abc123","version":"v2"},"error":{"status":500,"message":"oops","requestId":"abc123"}}`)
+}
+
+func (suite RespondSuite) TestEnvelope_options() {
+	var events []respond.EnvelopeEvent
+	factory := respond.NewFactory(respond.WithEnvelope(respond.EnvelopeOptions{
+		DataKey:  "result",
+		ErrorKey: "problem",
+		Meta: func(event respond.EnvelopeEvent) interface{} {
+			events = append(events, event)
+			return nil
+		},
+	}))
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Accepted("hello")
+	suite.assertStatus(w, 202)
+	suite.assertBody(w, `{"result":"hello","meta":{},"problem":null}`)
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Fail(errors.New("nope"))
+	suite.assertBody(w, `{"result":null,"meta":{},"problem":{"status":500,"message":"nope"}}`)
+
+	suite.Require().Len(events, 2)
+	suite.Equal(202, events[0].Status)
+	suite.Equal("hello", events[0].Value)
+	suite.Nil(events[0].Failure)
+	suite.Equal(500, events[1].Status)
+	suite.Nil(events[1].Value)
+	suite.EqualError(events[1].Failure, "nope")
+}
+
+// Restricted fields, sparse fieldsets, and pages should all apply to the data in the envelope.
+func (suite RespondSuite) TestEnvelope_data() {
+	factory := newEnvelopeFactory(respond.WithSparseFields(""))
+
+	w := newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/accounts/a1", nil)).Ok(redactAccount{ID: "a1", PasswordHash: "x"})
+	suite.assertBody(w, `{"data":{"id":"a1","email":"[REDACTED]","ssn":"[REDACTED]","balance":"0"},"meta":{"requestId":"","version":"v2"},"error":null}`)
+
+	w = newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/accounts/a1?fields=id", nil)).Ok(redactAccount{ID: "a1"})
+	suite.assertBody(w, `{"data":{"id":"a1"},"meta":{"requestId":"","version":"v2"},"error":null}`)
+
+	w = newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/accounts/a1?fields=nope", nil)).Ok(redactAccount{ID: "a1"})
+	suite.assertStatus(w, 400)
+	suite.assertBody(w, `{"data":null,"meta":{"requestId":"","version":"v2"},"error":{"status":400,"message":"unknown field: nope"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/users?page=1", nil)).Ok(respond.PageNumber([]mockUser{{ID: 1}}, 1, 10, 1))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Total-Count", "1")
+	suite.assertBody(w, `{"data":[{"id":1,"name":""}],"meta":{"requestId":"","version":"v2"},"error":null}`)
+
+	w = newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodHead, "/users/1", nil)).Ok(mockUser{ID: 1})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "79")
+	suite.assertEmptyBody(w)
+}
+
+// Responses that aren't plain JSON should never be wrapped.
+func (suite RespondSuite) TestEnvelope_untouched() {
+	factory := newEnvelopeFactory()
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok(rawContentReader{reader: newRawString("hello")})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "hello")
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).HTML("<h1>Hello</h1>")
+	suite.assertBody(w, "<h1>Hello</h1>")
+
+	w = newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/", nil)).Redirect("/login")
+	suite.assertStatus(w, 307)
+	suite.assertHeader(w, "Location", "/login")
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).NoContent()
+	suite.assertStatus(w, 204)
+	suite.assertEmptyBody(w)
+
+	w = newResponseWriter()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/orders/", nil)).Ok(halOrder{ID: "5"})
+	suite.assertHeader(w, "Content-Type", "application/hal+json")
+	suite.assertBody(w, `{"_links":{"self":{"href":"/orders/5"}},"id":"5","total":0}`)
+}
@@ -14,8 +14,9 @@ var errorResponseUnknown = errorResponse{
 // enough information to respond with a meaningful error message and an appropriate
 // 4XX/5XX status code to indicate the type of failure.
 type errorResponse struct {
-	Status  int    `json:"status"`
-	Message string `json:"message,omitempty"`
+	Status    int    `json:"status"`
+	Message   string `json:"message,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
 }
 
 // Status returns the HTTP status code you want to respond to the user with.
@@ -86,3 +87,24 @@ func toErrorResponse(err error) errorResponse {
 		Message: err.Error(),
 	}
 }
+
+// statusSource unwraps the error looking for the error in the chain w/ a Status(), StatusCode(),
+// or Code() function; the one that determines the HTTP status we'll fail with. It returns nil when
+// no error in the chain has one, meaning that we'll fail w/ a 500.
+func statusSource(err error) error {
+	var errStatus ErrorWithStatus
+	if errors.As(err, &errStatus) {
+		return errStatus
+	}
+
+	var errStatusCode ErrorWithStatusCode
+	if errors.As(err, &errStatusCode) {
+		return errStatusCode
+	}
+
+	var errCode ErrorWithCode
+	if errors.As(err, &errCode) {
+		return errCode
+	}
+	return nil
+}
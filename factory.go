@@ -0,0 +1,79 @@
+package respond
+
+import (
+	"net/http"
+)
+
+// defaultFactory is the factory used by the package-level To() function. It has no
+// customizations, so responders behave exactly as described by the Responder docs.
+var defaultFactory = NewFactory()
+
+// Factory creates Responders that all share the same configuration. Most services can just
+// use the package-level To() function, but if you want to customize how responders behave
+// (e.g. restricting where redirects can go), create a factory once and use its To() function
+// in your handlers instead.
+//
+//	responders := respond.NewFactory(
+//	    respond.WithRedirectPolicy(respond.RedirectPolicy{...}),
+//	)
+//
+//	func MyHandler(w http.ResponseWriter, req *http.Request) {
+//	    response := responders.To(w, req)
+//	    ...
+//	}
+type Factory struct {
+	redirectPolicy   *RedirectPolicy
+	mimeTypes        map[string]string
+	securityHeaders  *SecurityHeaders
+	requestID        *RequestIDOptions
+	beforeWrite      []BeforeWriteHook
+	afterWrite       []AfterWriteHook
+	onFail           []FailHook
+	fieldsParam      string
+	jsonAPI          bool
+	envelope         *EnvelopeOptions
+	emptyCollections bool
+	encoder          Encoder
+}
+
+// FactoryOption defines a customization you can apply to a Factory when you create it.
+type FactoryOption func(factory *Factory)
+
+// NewFactory creates a responder factory with the given customizations applied.
+func NewFactory(options ...FactoryOption) *Factory {
+	factory := &Factory{
+		mimeTypes: map[string]string{},
+	}
+	for ext, contentType := range defaultMIMETypes {
+		factory.mimeTypes[ext] = contentType
+	}
+	for _, option := range options {
+		option(factory)
+	}
+	return factory
+}
+
+// To creates a "Responder" that replies to the inputs for the given HTTP request using this
+// factory's configuration. It's the factory equivalent of the package-level To() function.
+func (factory *Factory) To(w http.ResponseWriter, req *http.Request) Responder {
+	responder := Responder{
+		writer:    w,
+		request:   req,
+		factory:   factory,
+		requestID: factory.resolveRequestID(w, req),
+		state:     &responseState{},
+	}
+	if factory.securityHeaders.needsNonce() {
+		responder.nonce = newCSPNonce()
+	}
+	return responder
+}
+
+// WithRedirectPolicy restricts where the factory's responders are allowed to redirect to. This
+// applies to every redirect the responder performs; Redirect(), RedirectTo(), Redirector values
+// passed to Reply(), and so on.
+func WithRedirectPolicy(policy RedirectPolicy) FactoryOption {
+	return func(factory *Factory) {
+		factory.redirectPolicy = &policy
+	}
+}
@@ -0,0 +1,339 @@
+package respond
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fieldTree is the parsed form of a sparse fieldset such as "id,user.name,user.email". Each key is
+// a JSON field name, and its subtree contains the nested fields to keep. An empty subtree means
+// that we keep the entire value of that field.
+type fieldTree map[string]fieldTree
+
+// WithSparseFields lets callers trim the JSON responses from the factory's responders using a query
+// string parameter such as "?fields=id,name,items.id". Nested fields are separated by dots, and they
+// apply to every element when the value is a slice. Requesting a field that your value doesn't have
+// results in a 400 error. When the param name is empty, we use "fields".
+func WithSparseFields(param string) FactoryOption {
+	if param == "" {
+		param = "fields"
+	}
+	return func(factory *Factory) {
+		factory.fieldsParam = param
+	}
+}
+
+// sparseFields is the sparse fieldset that the caller asked for.
+type sparseFields struct {
+	// tree contains the fields to keep.
+	tree fieldTree
+	// dynamic tracks the subtrees that apply to values whose fields we can't know from their type
+	// (maps, interfaces, and types w/ custom JSON), keyed by the subtree's identity. We check those
+	// against the JSON as we prune it instead.
+	dynamic map[uintptr]*dynamicFields
+}
+
+// dynamicFields keeps track of what we found in the JSON for one of the subtrees that we couldn't
+// validate up front.
+type dynamicFields struct {
+	// path is the dotted path of the subtree in the original fieldset (e.g. "user.labels.").
+	path string
+	// names are the fields that the caller asked for at this level.
+	names []string
+	// seen contains the names that we found in at least one JSON object.
+	seen map[string]bool
+	// objects indicates that we found at least one JSON object to look for the fields in.
+	objects bool
+	// scalar indicates that we found a string, number, or boolean, which doesn't have fields at all.
+	scalar bool
+}
+
+// requestedFields parses the sparse fieldset from the request and makes sure that every field
+// actually exists on the value's type. It returns nil if the caller didn't ask for specific
+// fields or the factory doesn't support them.
+func (r Responder) requestedFields(value interface{}) (*sparseFields, error) {
+	if r.factory.fieldsParam == "" || r.request == nil || r.request.URL == nil {
+		return nil, nil
+	}
+	param := r.request.URL.Query().Get(r.factory.fieldsParam)
+	if param == "" {
+		return nil, nil
+	}
+
+	tree, err := parseFields(param)
+	if err != nil {
+		return nil, err
+	}
+	fields := &sparseFields{tree: tree, dynamic: map[uintptr]*dynamicFields{}}
+	if err = fields.validate(tree, reflect.TypeOf(value), "", r.View()); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// parseFields converts a comma-separated list of dotted field paths into a fieldTree. Asking for
+// both a field and one of its nested fields (e.g. "user,user.name") keeps the entire field.
+func parseFields(param string) (fieldTree, error) {
+	fields := fieldTree{}
+	for _, path := range strings.Split(param, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		tree := fields
+		segments := strings.Split(path, ".")
+		for i, segment := range segments {
+			if segment == "" {
+				return nil, errorResponse{Status: 400, Message: "invalid field: " + path}
+			}
+			if i == len(segments)-1 {
+				// The caller wants the whole field, so forget about any nested fields they asked for.
+				tree[segment] = fieldTree{}
+				break
+			}
+
+			subtree, ok := tree[segment]
+			if ok && len(subtree) == 0 {
+				// The caller already asked for the whole field, so there's nothing more to add.
+				break
+			}
+			if !ok {
+				subtree = fieldTree{}
+				tree[segment] = subtree
+			}
+			tree = subtree
+		}
+	}
+	return fields, nil
+}
+
+// validate makes sure that every field in the tree exists on the given type, returning a 400 error
+// w/ the path of the first one that doesn't. Maps, interfaces, and types w/ custom JSON marshaling
+// could have any fields, so we hold onto those subtrees and check them when we prune the JSON. Fields
+// that the view isn't allowed to see (and aren't just redacted) don't exist as far as the caller is concerned.
+func (fields *sparseFields) validate(tree fieldTree, t reflect.Type, path string, view string) error {
+	if len(tree) == 0 || t == nil {
+		return nil
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if hasCustomJSON(t) || t.Kind() == reflect.Interface {
+		fields.checkLater(tree, path)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			break // []byte is marshaled as a base64 string, not an array
+		}
+		return fields.validate(tree, t.Elem(), path, view)
+	case reflect.Map:
+		fields.checkLater(tree, path)
+		for _, name := range tree.names() {
+			if err := fields.validate(tree[name], t.Elem(), path+name+".", view); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		info := structFields(t)
+		for _, name := range tree.names() {
+			i, ok := info.byName[name]
+			if !ok || (!info.fields[i].redact && !info.fields[i].visibleTo(view)) {
+				return errorResponse{Status: 400, Message: "unknown field: " + path + name}
+			}
+			if err := fields.validate(tree[name], info.fields[i].typ, path+name+".", view); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errorResponse{Status: 400, Message: "unknown field: " + path + tree.names()[0]}
+}
+
+// checkLater remembers that we need to make sure the subtree's fields show up in the JSON.
+func (fields *sparseFields) checkLater(tree fieldTree, path string) {
+	fields.dynamic[tree.id()] = &dynamicFields{path: path, names: tree.names(), seen: map[string]bool{}}
+}
+
+// check returns a 400 error for the first field that we couldn't validate up front and didn't find
+// while pruning the JSON: either it wasn't in any of the objects we looked in, or it was nested under
+// a value that doesn't have fields at all (e.g. "name.first" when "name" is a string).
+func (fields *sparseFields) check() error {
+	if fields == nil {
+		return nil
+	}
+
+	var unknown []string
+	for _, dynamic := range fields.dynamic {
+		for _, name := range dynamic.names {
+			if dynamic.scalar || (dynamic.objects && !dynamic.seen[name]) {
+				unknown = append(unknown, dynamic.path+name)
+			}
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return errorResponse{Status: 400, Message: "unknown field: " + unknown[0]}
+}
+
+// wrap returns a copy of the fieldset that applies to a value that we've wrapped in another object
+// (e.g. an envelope), where the tree nests this fieldset's tree under the value's key.
+func (fields *sparseFields) wrap(tree fieldTree) *sparseFields {
+	return &sparseFields{tree: tree, dynamic: fields.dynamic}
+}
+
+// id returns a value that uniquely identifies this subtree, even after it's been nested inside of
+// another tree (e.g. when we wrap a value in an envelope).
+func (tree fieldTree) id() uintptr {
+	return reflect.ValueOf(tree).Pointer()
+}
+
+// names returns the tree's top-level field names in a consistent (alphabetical) order.
+func (tree fieldTree) names() []string {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// prune appends the subset of the compact JSON value that contains only the fields in the sparse
+// fieldset. Call check() afterwards to find out if any of the fields we couldn't validate up front
+// were missing from the JSON.
+func (fields *sparseFields) prune(dst []byte, data []byte) []byte {
+	return fields.pruneTree(fields.tree, dst, data)
+}
+
+// pruneTree appends the subset of the JSON value (as generated by json.Marshal) that contains only the
+// fields in the tree. We walk the encoded bytes directly rather than unmarshaling into a map and
+// marshaling that back, so the original field order and number formatting are preserved, too.
+func (fields *sparseFields) pruneTree(tree fieldTree, dst []byte, data []byte) []byte {
+	if len(tree) == 0 || len(data) == 0 {
+		return append(dst, data...)
+	}
+
+	dynamic := fields.dynamic[tree.id()]
+	switch data[0] {
+	case '{':
+		if dynamic != nil {
+			dynamic.objects = true
+		}
+		dst = append(dst, '{')
+		wrote := false
+		for i := 1; i < len(data) && data[i] != '}'; {
+			keyEnd := jsonStringEnd(data, i)
+			valueStart := keyEnd + 1 // skip the ':'
+			valueEnd := jsonValueEnd(data, valueStart)
+
+			key := jsonKey(data[i:keyEnd])
+			if subtree, ok := tree[key]; ok {
+				if wrote {
+					dst = append(dst, ',')
+				}
+				if dynamic != nil {
+					dynamic.seen[key] = true
+				}
+				dst = append(dst, data[i:valueStart]...)
+				dst = fields.pruneTree(subtree, dst, data[valueStart:valueEnd])
+				wrote = true
+			}
+
+			i = valueEnd
+			if i < len(data) && data[i] == ',' {
+				i++
+			}
+		}
+		return append(dst, '}')
+
+	case '[':
+		dst = append(dst, '[')
+		for i := 1; i < len(data) && data[i] != ']'; {
+			valueEnd := jsonValueEnd(data, i)
+			if i > 1 {
+				dst = append(dst, ',')
+			}
+			dst = fields.pruneTree(tree, dst, data[i:valueEnd])
+
+			i = valueEnd
+			if i < len(data) && data[i] == ',' {
+				i++
+			}
+		}
+		return append(dst, ']')
+
+	case 'n':
+		// A null (e.g. a nil pointer or map) has no fields to prune, but it's not wrong to ask for them.
+		return append(dst, data...)
+
+	default:
+		// Strings, numbers, and booleans have no fields, so the caller shouldn't have asked for any.
+		if dynamic != nil {
+			dynamic.scalar = true
+		}
+		return append(dst, data...)
+	}
+}
+
+// jsonValueEnd returns the index just past the end of the compact JSON value that starts at index i.
+func jsonValueEnd(data []byte, i int) int {
+	switch data[i] {
+	case '"':
+		return jsonStringEnd(data, i)
+	case '{', '[':
+		depth := 0
+		for ; i < len(data); i++ {
+			switch data[i] {
+			case '"':
+				i = jsonStringEnd(data, i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(data)
+	default:
+		for ; i < len(data); i++ {
+			if c := data[i]; c == ',' || c == '}' || c == ']' {
+				return i
+			}
+		}
+		return len(data)
+	}
+}
+
+// jsonStringEnd returns the index just past the closing quote of the JSON string that starts at index i.
+func jsonStringEnd(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// jsonKey converts a quoted JSON object key into the field name it represents.
+func jsonKey(quoted []byte) string {
+	if !strings.ContainsRune(string(quoted), '\\') {
+		return string(quoted[1 : len(quoted)-1])
+	}
+	var key string
+	_ = json.Unmarshal(quoted, &key)
+	return key
+}
@@ -0,0 +1,217 @@
+package respond_test
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+type fieldsAddress struct {
+	City    string `json:"city"`
+	Country string `json:"country,omitempty"`
+}
+
+type fieldsBase struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type fieldsUser struct {
+	fieldsBase
+	Name    string            `json:"name"`
+	Email   string            `json:"email,omitempty"`
+	Address *fieldsAddress    `json:"address"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Secret  string            `json:"-"`
+	Avatar  []byte            `json:"avatar,omitempty"`
+	Notes   string
+}
+
+type fieldsOrder struct {
+	ID    string            `json:"id"`
+	User  fieldsUser        `json:"user"`
+	Items []fieldsOrderItem `json:"items"`
+}
+
+type fieldsOrderItem struct {
+	ID       string  `json:"id"`
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+}
+
+func newFieldsUser() fieldsUser {
+	return fieldsUser{
+		fieldsBase: fieldsBase{ID: "u1", CreatedAt: time.Date(2020, 5, 12, 8, 30, 0, 0, time.UTC)},
+		Name:       "Bob",
+		Email:      "bob@example.com",
+		Address:    &fieldsAddress{City: "Boston", Country: "US"},
+		Tags:       []string{"a", "b"},
+		Notes:      "a \"quoted\" {note}, w/ [brackets]",
+	}
+}
+
+func newFieldsOrder() fieldsOrder {
+	return fieldsOrder{
+		ID:   "o1",
+		User: newFieldsUser(),
+		Items: []fieldsOrderItem{
+			{ID: "i1", Price: 1.5, Quantity: 2},
+			{ID: "i2", Price: 10, Quantity: 1},
+		},
+	}
+}
+
+func (suite RespondSuite) respondFields(uri string, value interface{}) *mockResponseWriter {
+	w := newResponseWriter()
+	req := newHTTPRequest(http.MethodGet, uri)
+	respond.NewFactory(respond.WithSparseFields("")).To(w, req).Ok(value)
+	return w
+}
+
+// Sparse fieldsets are opt-in, so the default responders should ignore the param.
+func (suite RespondSuite) TestFields_disabled() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/orders/o1?fields=id")).Ok(fieldsOrderItem{ID: "i1", Price: 1.5})
+	suite.assertBody(w, `{"id":"i1","price":1.5,"quantity":0}`)
+}
+
+func (suite RespondSuite) TestFields_topLevel() {
+	w := suite.respondFields("/users/u1?fields=name,id,notes", fieldsUser{Name: "Bob", Notes: "x"})
+	suite.assertError(w, 400, "unknown field: notes")
+
+	w = suite.respondFields("/users/u1?fields=name,id,Notes", newFieldsUser())
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"id":"u1","name":"Bob","Notes":"a \"quoted\" {note}, w/ [brackets]"}`)
+	suite.assertHeader(w, "Content-Length", "69")
+}
+
+func (suite RespondSuite) TestFields_nested() {
+	w := suite.respondFields("/orders/o1?fields=user.name,user.address.city,items.id", newFieldsOrder())
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"user":{"name":"Bob","address":{"city":"Boston"}},"items":[{"id":"i1"},{"id":"i2"}]}`)
+
+	// Asking for the whole field as well as one of its nested fields means you get the whole thing.
+	w = suite.respondFields("/orders/o1?fields=user.address.city,user.address,id", newFieldsOrder())
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"id":"o1","user":{"address":{"city":"Boston","country":"US"}}}`)
+
+	w = suite.respondFields("/orders/o1?fields=items.price,items.nope", newFieldsOrder())
+	suite.assertError(w, 400, "unknown field: items.nope")
+
+	w = suite.respondFields("/orders/o1?fields=id.nope", newFieldsOrder())
+	suite.assertError(w, 400, "unknown field: id.nope")
+
+	w = suite.respondFields("/orders/o1?fields=user..name", newFieldsOrder())
+	suite.assertError(w, 400, "invalid field: user..name")
+}
+
+// Slices of values should apply the fields to every element.
+func (suite RespondSuite) TestFields_slice() {
+	users := []fieldsUser{newFieldsUser(), {Name: "Alice"}}
+	w := suite.respondFields("/users?fields=name,address.city,tags", users)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `[{"name":"Bob","address":{"city":"Boston"},"tags":["a","b"]},{"name":"Alice","address":null,"tags":null}]`)
+
+	w = suite.respondFields("/users?fields=name", []fieldsUser{})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `[]`)
+
+	w = suite.respondFields("/users?fields=nope", []fieldsUser{})
+	suite.assertError(w, 400, "unknown field: nope")
+}
+
+// Maps and interfaces can have any fields, so we check the ones you ask for against their JSON instead.
+func (suite RespondSuite) TestFields_maps() {
+	value := map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": 2, "d": 3},
+		"e": []interface{}{map[string]interface{}{"f": 4, "g": 5}, map[string]interface{}{"g": 6}},
+		"h": nil,
+	}
+	w := suite.respondFields("/things?fields=b.c,e.f,h.i", value)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"b":{"c":2},"e":[{"f":4},{}],"h":null}`)
+
+	w = suite.respondFields("/users?fields=labels.team", fieldsUser{Labels: map[string]string{"team": "x", "env": "y"}})
+	suite.assertBody(w, `{"labels":{"team":"x"}}`)
+
+	w = suite.respondFields("/things?fields=b.c,z", value)
+	suite.assertError(w, 400, "unknown field: z")
+
+	w = suite.respondFields("/things?fields=e.z", value)
+	suite.assertError(w, 400, "unknown field: e.z")
+
+	w = suite.respondFields("/things?fields=a.b", value)
+	suite.assertError(w, 400, "unknown field: a.b")
+
+	w = suite.respondFields("/things?fields=nope", map[string]int{"a": 1})
+	suite.assertError(w, 400, "unknown field: nope")
+
+	w = suite.respondFields("/things?fields=nope", map[string]int{})
+	suite.assertError(w, 400, "unknown field: nope")
+
+	// There aren't any objects in an empty list, so there's nothing to check against.
+	w = suite.respondFields("/things?fields=nope", []map[string]int{})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `[]`)
+}
+
+// Fields that are promoted from embedded structs or marshal themselves should work like any other.
+func (suite RespondSuite) TestFields_embeddedAndCustom() {
+	w := suite.respondFields("/users/u1?fields=id,createdAt", newFieldsUser())
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"id":"u1","createdAt":"2020-05-12T08:30:00Z"}`)
+
+	// We don't know how a type w/ custom marshaling is structured until we see its JSON.
+	w = suite.respondFields("/users/u1?fields=createdAt.year", newFieldsUser())
+	suite.assertError(w, 400, "unknown field: createdAt.year")
+
+	w = suite.respondFields("/users/u1?fields=Secret", newFieldsUser())
+	suite.assertError(w, 400, "unknown field: Secret")
+
+	w = suite.respondFields("/users/u1?fields=avatar.size", newFieldsUser())
+	suite.assertError(w, 400, "unknown field: avatar.size")
+}
+
+func (suite RespondSuite) TestFields_page() {
+	page := respond.PageNumber([]fieldsOrderItem{{ID: "i1", Price: 2}}, 1, 10, 1)
+	w := suite.respondFields("/items?fields=id", page)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `[{"id":"i1"}]`)
+
+	page.Envelope = true
+	w = suite.respondFields("/items?fields=id", page)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"items":[{"id":"i1"}],"meta":{"page":1,"pageSize":10,"totalItems":1,"totalPages":1}}`)
+
+	w = suite.respondFields("/items?fields=meta", page)
+	suite.assertError(w, 400, "unknown field: meta")
+
+	// Fields we check against the JSON should still be relative to the items, not the envelope.
+	page = respond.PageNumber([]map[string]int{{"a": 1}}, 1, 10, 1)
+	page.Envelope = true
+	w = suite.respondFields("/items?fields=a", page)
+	suite.assertBody(w, `{"items":[{"a":1}],"meta":{"page":1,"pageSize":10,"totalItems":1,"totalPages":1}}`)
+
+	w = suite.respondFields("/items?fields=b", page)
+	suite.assertError(w, 400, "unknown field: b")
+}
+
+// Errors and non-JSON responses should never be pruned.
+func (suite RespondSuite) TestFields_notJSON() {
+	factory := respond.NewFactory(respond.WithSparseFields("only"))
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/users?only=id")).NotFound("user not found")
+	suite.assertError(w, 404, "user not found")
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/users?only=id")).Ok(fieldsOrderItem{ID: "i1"})
+	suite.assertBody(w, `{"id":"i1"}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/users?only=id")).Ok(nil)
+	suite.assertBody(w, `null`)
+}
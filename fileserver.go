@@ -0,0 +1,224 @@
+package respond
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// FileServerOptions customizes how FileServer() and ServeFile() resolve and deliver files.
+type FileServerOptions struct {
+	// IndexFiles are the file names we look for (in order) when the request points at a
+	// directory. When this is empty, we'll look for "index.html". If a directory has none of
+	// these files, we respond with a 404; we never render directory listings.
+	IndexFiles []string
+	// AllowDotFiles lets callers request files/directories whose names start with a "." such
+	// as ".env" or ".git/config". By default, these respond with a 404 as if they don't exist.
+	AllowDotFiles bool
+	// SPAFallback is the file we serve instead of a 404 when the requested path doesn't exist,
+	// typically "index.html" so that your single-page app's client-side router can take over. We
+	// only fall back for paths w/o a file extension, so a missing "app.js" is still a 404.
+	SPAFallback string
+	// Factory is the responder factory used to respond to each request. When this is nil, we
+	// use the same default responders as the package-level To() function.
+	Factory *Factory
+}
+
+// FileServer creates an HTTP handler that serves the files in the given file system (e.g. an
+// embed.FS w/ your frontend assets). It's similar to http.FileServer, but it never renders
+// directory listings, it hides dotfiles, and failures are respond-style JSON errors (or simple
+// HTML pages when the caller accepts HTML) rather than plain text. Like Serve(), the file's
+// modification time and size are used to support conditional and Range requests.
+func FileServer(fsys fs.FS, options FileServerOptions) http.Handler {
+	factory := options.Factory
+	if factory == nil {
+		factory = defaultFactory
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		response := factory.To(w, req)
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			response.failFile(errorResponse{Status: http.StatusMethodNotAllowed, Message: "method not allowed: " + req.Method})
+			return
+		}
+		response.serveFS(fsys, req.URL.Path, options, true)
+	})
+}
+
+// ServeFile responds with the contents of the named file in the given file system (e.g. an
+// embed.FS). The name is cleaned and resolved the same way FileServer() does it; we look for
+// "index.html" when it's a directory, and reject path traversal and dotfiles. The file's
+// modification time and size are used to support conditional and Range requests.
+func (r Responder) ServeFile(fsys fs.FS, name string) {
+	r.serveFS(fsys, name, FileServerOptions{}, false)
+}
+
+// serveFS resolves the file system path and serves the resulting file or an appropriate error. When
+// the name is the request's URL path, we redirect directories w/o a trailing slash to the ones with.
+func (r Responder) serveFS(fsys fs.FS, name string, options FileServerOptions, redirectDirs bool) {
+	if fsys == nil {
+		r.Fail(fmt.Errorf("unable to serve file from nil file system"))
+		return
+	}
+	if hasTraversal(name) {
+		r.failFile(errorResponse{Status: http.StatusForbidden, Message: "invalid file path: " + name})
+		return
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	if !options.AllowDotFiles && hasDotFile(name) {
+		r.failFile(errorResponse{Status: http.StatusNotFound, Message: "file not found: " + name})
+		return
+	}
+
+	file, stat, dir, err := openFile(fsys, name, options.IndexFiles)
+	if errors.Is(err, fs.ErrNotExist) && options.SPAFallback != "" && path.Ext(name) == "" && !dir {
+		file, stat, _, err = openFile(fsys, strings.TrimPrefix(options.SPAFallback, "/"), options.IndexFiles)
+	}
+	if dir && redirectDirs && r.request != nil && !strings.HasSuffix(r.request.URL.Path, "/") {
+		if err == nil {
+			_ = file.Close()
+		}
+		r.redirectDirectory()
+		return
+	}
+	if err != nil {
+		r.failFile(fileErrorResponse(err, name))
+		return
+	}
+
+	defer func() { _ = file.Close() }()
+
+	// We just opened the file, so its size is exactly what's left to read even if it can't seek.
+	info := statContent(file)
+	if info.size < 0 && stat.Mode().IsRegular() {
+		info.size = stat.Size()
+	}
+	r.serveContent(stat.Name(), "inline", file, info)
+}
+
+// redirectDirectory sends a request for a directory w/o a trailing slash (e.g. "/docs") to the one w/
+// the slash, so that relative links in its index file resolve against the directory rather than its
+// parent. Like http.FileServer, the location is relative so that it works behind http.StripPrefix().
+func (r Responder) redirectDirectory() {
+	location := (&url.URL{Path: path.Base(r.request.URL.Path) + "/", RawQuery: r.request.URL.RawQuery}).String()
+	_ = r.write(http.StatusMovedPermanently, location, func(w http.ResponseWriter) error {
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return nil
+	})
+}
+
+// openFile opens the named file in the file system. If that file is a directory, we'll look for
+// the first available index file in that directory instead, indicating that it was a directory.
+func openFile(fsys fs.FS, name string, indexFiles []string) (fs.File, fs.FileInfo, bool, error) {
+	file, stat, err := openStat(fsys, name)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !stat.IsDir() {
+		return file, stat, false, nil
+	}
+
+	_ = file.Close()
+	if len(indexFiles) == 0 {
+		indexFiles = []string{"index.html"}
+	}
+	for _, indexFile := range indexFiles {
+		file, stat, err = openStat(fsys, path.Join(name, indexFile))
+		if err == nil && !stat.IsDir() {
+			return file, stat, true, nil
+		}
+		if err == nil {
+			_ = file.Close()
+		}
+	}
+	return nil, nil, true, fs.ErrNotExist
+}
+
+// openStat opens the named file and grabs its info.
+func openStat(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	return file, stat, nil
+}
+
+// hasTraversal determines if the raw path contains any ".." segments or other sketchy
+// characters that someone might use to escape the root of the file system.
+func hasTraversal(name string) bool {
+	if strings.ContainsAny(name, "\x00\\") {
+		return true
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDotFile determines if any segment of the cleaned path starts with a ".".
+func hasDotFile(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") && segment != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// fileErrorResponse converts errors from the file system into the appropriate 4XX/5XX error.
+func fileErrorResponse(err error, name string) errorResponse {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		return errorResponse{Status: http.StatusNotFound, Message: "file not found: " + name}
+	case errors.Is(err, fs.ErrPermission):
+		return errorResponse{Status: http.StatusForbidden, Message: "permission denied: " + name}
+	default:
+		return toErrorResponse(err)
+	}
+}
+
+// failFile responds with the error as a standard JSON error unless the caller accepts HTML, in
+// which case they get a simple HTML error page. Browsers requesting missing pages from a file
+// server shouldn't be greeted with a blob of JSON.
+func (r Responder) failFile(err errorResponse) {
+	if r.request == nil || !strings.Contains(r.request.Header.Get("Accept"), "text/html") {
+		r.Fail(err)
+		return
+	}
+
+	title := html.EscapeString(fmt.Sprintf("%d %s", err.Status, http.StatusText(err.Status)))
+	r.writeSecurityHeaders()
+	r.fail(err)
+	r.failure = err
+	r.writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = r.write(err.Status, err, func(w http.ResponseWriter) error {
+		w.WriteHeader(err.Status)
+		if r.isHead() {
+			return nil
+		}
+		_, writeErr := fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>%s</title></head><body><h1>%s</h1><p>%s</p></body></html>\n",
+			title,
+			title,
+			html.EscapeString(err.Message),
+		)
+		return writeErr
+	})
+}
@@ -0,0 +1,168 @@
+package respond_test
+
+import (
+	"net/http"
+	"testing/fstest"
+
+	"github.com/monadicstack/respond"
+)
+
+func newTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":         &fstest.MapFile{Data: []byte("<h1>home</h1>"), ModTime: contentModTime},
+		"app.js":             &fstest.MapFile{Data: []byte("alert(1);"), ModTime: contentModTime},
+		"docs/index.htm":     &fstest.MapFile{Data: []byte("<h1>docs</h1>")},
+		"docs/guide.txt":     &fstest.MapFile{Data: []byte("read me")},
+		"empty/nothing.txt":  &fstest.MapFile{Data: []byte("nothing")},
+		".env":               &fstest.MapFile{Data: []byte("SECRET=1")},
+		".well-known/x.json": &fstest.MapFile{Data: []byte("{}")},
+	}
+}
+
+func (suite RespondSuite) serveFS(method string, target string, options respond.FileServerOptions, headers ...string) *mockResponseWriter {
+	w := newResponseWriter()
+	respond.FileServer(newTestFS(), options).ServeHTTP(w, newHTTPRequest(method, target, headers...))
+	return w
+}
+
+func (suite RespondSuite) TestFileServer_file() {
+	w := suite.serveFS("GET", "/app.js", respond.FileServerOptions{})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/javascript; charset=utf-8")
+	suite.assertHeader(w, "Content-Length", "9")
+	suite.assertHeader(w, "Last-Modified", "Tue, 12 May 2020 08:30:15 GMT")
+	suite.assertBody(w, "alert(1);")
+}
+
+func (suite RespondSuite) TestFileServer_index() {
+	w := suite.serveFS("GET", "/", respond.FileServerOptions{})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/html; charset=utf-8")
+	suite.assertBody(w, "<h1>home</h1>")
+
+	w = suite.serveFS("GET", "/docs/", respond.FileServerOptions{IndexFiles: []string{"index.html", "index.htm"}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "<h1>docs</h1>")
+
+	// No directory listings when there's no index file.
+	w = suite.serveFS("GET", "/empty/", respond.FileServerOptions{})
+	suite.assertError(w, 404, "file not found: empty")
+}
+
+// Directories w/o a trailing slash should redirect so that relative links in the index file still work.
+func (suite RespondSuite) TestFileServer_directoryRedirect() {
+	w := suite.serveFS("GET", "/docs?lang=en", respond.FileServerOptions{IndexFiles: []string{"index.htm"}})
+	suite.assertStatus(w, 301)
+	suite.assertHeader(w, "Location", "docs/?lang=en")
+	suite.assertEmptyBody(w)
+
+	w = suite.serveFS("GET", "/empty", respond.FileServerOptions{})
+	suite.assertStatus(w, 301)
+	suite.assertHeader(w, "Location", "empty/")
+
+	// Behind http.StripPrefix(), the relative location still points at the right place.
+	w = newResponseWriter()
+	handler := http.StripPrefix("/static", respond.FileServer(newTestFS(), respond.FileServerOptions{}))
+	handler.ServeHTTP(w, newHTTPRequest("GET", "/static/docs"))
+	suite.assertStatus(w, 301)
+	suite.assertHeader(w, "Location", "docs/")
+
+	// Files don't get redirected, and neither does ServeFile() since its name isn't a URL.
+	w = suite.serveFS("GET", "/docs/guide.txt", respond.FileServerOptions{})
+	suite.assertStatus(w, 200)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest("GET", "/")).ServeFile(newTestFS(), "docs")
+	suite.assertError(w, 404, "file not found: docs")
+}
+
+func (suite RespondSuite) TestFileServer_notFound() {
+	w := suite.serveFS("GET", "/nope.txt", respond.FileServerOptions{})
+	suite.assertError(w, 404, "file not found: nope.txt")
+
+	w = suite.serveFS("GET", "/nope.txt", respond.FileServerOptions{}, "Accept", "text/html,*/*")
+	suite.assertStatus(w, 404)
+	suite.assertHeader(w, "Content-Type", "text/html; charset=utf-8")
+	suite.Require().Contains(string(w.Body), "<h1>404 Not Found</h1>")
+	suite.Require().Contains(string(w.Body), "file not found: nope.txt")
+}
+
+func (suite RespondSuite) TestFileServer_dotFiles() {
+	w := suite.serveFS("GET", "/.env", respond.FileServerOptions{})
+	suite.assertStatus(w, 404)
+
+	w = suite.serveFS("GET", "/.well-known/x.json", respond.FileServerOptions{})
+	suite.assertStatus(w, 404)
+
+	w = suite.serveFS("GET", "/.well-known/x.json", respond.FileServerOptions{AllowDotFiles: true})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "{}")
+}
+
+func (suite RespondSuite) TestFileServer_traversal() {
+	req := newHTTPRequest("GET", "/")
+	req.URL.Path = "/docs/../../etc/passwd"
+	w := newResponseWriter()
+	respond.FileServer(newTestFS(), respond.FileServerOptions{}).ServeHTTP(w, req)
+	suite.assertStatus(w, 403)
+
+	req.URL.Path = `/docs\guide.txt`
+	w = newResponseWriter()
+	respond.FileServer(newTestFS(), respond.FileServerOptions{}).ServeHTTP(w, req)
+	suite.assertStatus(w, 403)
+}
+
+func (suite RespondSuite) TestFileServer_spaFallback() {
+	options := respond.FileServerOptions{SPAFallback: "index.html"}
+
+	w := suite.serveFS("GET", "/users/123/settings", options)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/html; charset=utf-8")
+	suite.assertBody(w, "<h1>home</h1>")
+
+	// Missing assets should still be 404s.
+	w = suite.serveFS("GET", "/missing.js", options)
+	suite.assertStatus(w, 404)
+
+	// Real files are still served as-is.
+	w = suite.serveFS("GET", "/docs/guide.txt", options)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "read me")
+}
+
+func (suite RespondSuite) TestFileServer_conditional() {
+	w := suite.serveFS("GET", "/app.js", respond.FileServerOptions{}, "If-Modified-Since", "Tue, 12 May 2020 08:30:15 GMT")
+	suite.assertStatus(w, 304)
+	suite.assertEmptyBody(w)
+
+	w = suite.serveFS("GET", "/app.js", respond.FileServerOptions{}, "Range", "bytes=0-4")
+	suite.assertStatus(w, 206)
+	suite.assertBody(w, "alert")
+}
+
+func (suite RespondSuite) TestFileServer_methodNotAllowed() {
+	w := suite.serveFS("POST", "/app.js", respond.FileServerOptions{})
+	suite.assertError(w, 405, "method not allowed: POST")
+	suite.assertHeader(w, "Allow", "GET, HEAD")
+}
+
+func (suite RespondSuite) TestServeFile() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest("GET", "/")).ServeFile(newTestFS(), "docs/guide.txt")
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertBody(w, "read me")
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest("GET", "/")).ServeFile(newTestFS(), "/")
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "<h1>home</h1>")
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest("GET", "/")).ServeFile(newTestFS(), "../index.html")
+	suite.assertStatus(w, 403)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest("GET", "/")).ServeFile(nil, "index.html")
+	suite.assertStatus(w, 500)
+}
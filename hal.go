@@ -0,0 +1,216 @@
+package respond
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// HALContentType is the media type of HAL (Hypertext Application Language) documents.
+const HALContentType = "application/hal+json"
+
+// HALLink is a single link in a HAL resource's "_links". Relative hrefs (e.g. "orders/5" or
+// "../users/1") are resolved against the URL of the request you're responding to, so your
+// handlers don't need to know the external host or path prefix of your service.
+type HALLink struct {
+	// Href is the URL of the linked resource, or a URI template if Templated is true.
+	Href string `json:"href"`
+	// Templated indicates that the Href is an RFC 6570 URI template (e.g. "/orders{?page,size}").
+	Templated bool `json:"templated,omitempty"`
+	// Type is the expected media type of the linked resource.
+	Type string `json:"type,omitempty"`
+	// Name is a secondary key for selecting between links w/ the same relation.
+	Name string `json:"name,omitempty"`
+	// Title is a human-readable description of the link.
+	Title string `json:"title,omitempty"`
+}
+
+// HALResource is a value that includes HAL links to itself and related resources. When you respond
+// w/ one, we'll add a "_links" member to its JSON object and use the "application/hal+json" content
+// type. If you'd rather not implement this on your own types, wrap your value in a HAL instead.
+type HALResource interface {
+	// HALLinks returns the resource's links, keyed by their relation (e.g. "self", "next", "author").
+	HALLinks() map[string]HALLink
+}
+
+// HALEmbedder is a HALResource that also includes related resources in its "_embedded" member.
+type HALEmbedder interface {
+	// HALEmbedded returns the resource's embedded resources, keyed by their relation. The values can
+	// be single values or slices, and they can be HALResources w/ their own links, too.
+	HALEmbedded() map[string]interface{}
+}
+
+// HAL wraps a value that you want to respond with as a HAL resource.
+//
+//	response.Ok(respond.HAL{
+//	    Value: order,
+//	    Links: map[string]respond.HALLink{
+//	        "self":     {Href: ""},
+//	        "customer": {Href: "../customers/" + order.CustomerID},
+//	        "items":    {Href: "items{?page}", Templated: true},
+//	    },
+//	    Embedded: map[string]interface{}{
+//	        "items": order.Items,
+//	    },
+//	})
+type HAL struct {
+	// Value is the resource itself. It must marshal to a JSON object (or null).
+	Value interface{}
+	// Links are the resource's links, keyed by their relation.
+	Links map[string]HALLink
+	// Embedded are the related resources to include, keyed by their relation.
+	Embedded map[string]interface{}
+}
+
+// HALLinks returns the wrapper's links.
+func (hal HAL) HALLinks() map[string]HALLink {
+	return hal.Links
+}
+
+// HALEmbedded returns the wrapper's embedded resources.
+func (hal HAL) HALEmbedded() map[string]interface{} {
+	return hal.Embedded
+}
+
+// writeHAL writes the resource as a HAL document. Sparse fieldsets apply to the resource's own
+// fields, so we always keep its "_links" and "_embedded".
+func (r Responder) writeHAL(status int, resource HALResource) {
+	fields, err := r.requestedFields(halValue(resource))
+	if err != nil {
+		r.Fail(err)
+		return
+	}
+	if fields != nil {
+		fields.tree["_links"] = fieldTree{}
+		fields.tree["_embedded"] = fieldTree{}
+	}
+
+	document := halDocument{resource: resource}
+	if r.request != nil {
+		document.base = r.request.URL
+	}
+	r.writeBody(status, HALContentType, resource, document, fields)
+}
+
+// halValue returns the part of the resource that we marshal as its JSON object.
+func halValue(resource HALResource) interface{} {
+	if hal, ok := resource.(HAL); ok {
+		return hal.Value
+	}
+	return resource
+}
+
+// halDocument is a HALResource that we write as the resource's JSON object w/ its "_links" and
+// "_embedded" members added to the front.
+type halDocument struct {
+	resource HALResource
+	base     *url.URL
+}
+
+var halDocumentType = reflect.TypeOf(halDocument{})
+
+// members returns the document's "_links" and "_embedded" members, if it has any.
+func (document halDocument) members() jsonObject {
+	var members jsonObject
+	if links := document.resource.HALLinks(); len(links) > 0 {
+		resolved := make(map[string]HALLink, len(links))
+		for rel, link := range links {
+			resolved[rel] = document.resolve(link)
+		}
+		members = append(members, jsonMember{name: "_links", value: resolved})
+	}
+	if embedder, ok := document.resource.(HALEmbedder); ok {
+		if embedded := embedder.HALEmbedded(); len(embedded) > 0 {
+			values := make(map[string]interface{}, len(embedded))
+			for rel, embeddedValue := range embedded {
+				values[rel] = document.embed(embeddedValue)
+			}
+			members = append(members, jsonMember{name: "_embedded", value: values})
+		}
+	}
+	return members
+}
+
+// appendHAL writes the document's members followed by the members of the resource's own JSON object.
+func (w *jsonWriter) appendHAL(dst []byte, document halDocument) ([]byte, error) {
+	var err error
+	members := document.members()
+	if len(members) > 0 {
+		if dst, err = w.appendObject(dst, members); err != nil {
+			return dst, err
+		}
+		dst = dst[:len(dst)-1] // reopen the object so that the value's members can follow
+	}
+
+	start := len(dst)
+	value := halValue(document.resource)
+	if dst, err = w.append(dst, reflect.ValueOf(value), w.emptyCollections); err != nil {
+		return dst, err
+	}
+	valueJSON := bytes.TrimSpace(dst[start:])
+	if bytes.Equal(valueJSON, []byte("null")) {
+		valueJSON = []byte("{}")
+	}
+	if len(valueJSON) == 0 || valueJSON[0] != '{' {
+		return dst, fmt.Errorf("hal resource must be a JSON object: %T", value)
+	}
+	if len(members) == 0 {
+		return append(dst[:start], valueJSON...), nil
+	}
+
+	// Drop the opening brace of the value to merge its members (and closing brace) into ours.
+	valueJSON = valueJSON[1:]
+	dst = dst[:start]
+	if len(bytes.TrimSpace(valueJSON[:len(valueJSON)-1])) > 0 {
+		dst = append(dst, ',')
+	}
+	return append(dst, valueJSON...), nil
+}
+
+// embed prepares an embedded value for marshaling; embedded HALResources get their own links,
+// and everything else is written as-is (still subject to the "respond" tags on its fields).
+func (document halDocument) embed(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return value
+	}
+	if resource, ok := value.(HALResource); ok {
+		return halDocument{resource: resource, base: document.base}
+	}
+	if (v.Kind() == reflect.Slice && !v.IsNil() && v.Type().Elem().Kind() != reflect.Uint8) || v.Kind() == reflect.Array {
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = document.embed(v.Index(i).Interface())
+		}
+		return values
+	}
+	return value
+}
+
+// resolve converts the link's href to one that's relative to the request URL. For URI templates,
+// only the part before the first expression (e.g. "{?page}") is resolved.
+func (document halDocument) resolve(link HALLink) HALLink {
+	if document.base == nil {
+		return link
+	}
+
+	href, template := link.Href, ""
+	base := *document.base
+	if link.Templated {
+		if i := strings.IndexByte(href, '{'); i >= 0 {
+			href, template = href[:i], href[i:]
+		}
+		if href == "" {
+			base.RawQuery = "" // the template supplies its own query string
+		}
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return link
+	}
+	link.Href = base.ResolveReference(ref).String() + template
+	return link
+}
@@ -0,0 +1,119 @@
+package respond_test
+
+import (
+	"net/http"
+
+	"github.com/monadicstack/respond"
+)
+
+type halOrder struct {
+	ID     string `json:"id"`
+	Total  int    `json:"total"`
+	Secret string `json:"secret" respond:"omit"`
+}
+
+// HALLinks makes the order a HAL resource on its own, w/o the HAL wrapper.
+func (order halOrder) HALLinks() map[string]respond.HALLink {
+	return map[string]respond.HALLink{
+		"self": {Href: order.ID},
+	}
+}
+
+type halItem struct {
+	SKU string `json:"sku"`
+}
+
+func (suite RespondSuite) TestHAL_wrapper() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/api/v1/orders/5?expand=true")).Ok(respond.HAL{
+		Value: mockUser{ID: 5, Name: "Bob"},
+		Links: map[string]respond.HALLink{
+			"self":      {Href: ""},
+			"customer":  {Href: "../customers/7", Title: "Bob"},
+			"items":     {Href: "5/items{?page,size}", Templated: true},
+			"search":    {Href: "{?q}", Templated: true},
+			"external":  {Href: "https://example.com/docs", Type: "text/html"},
+			"root":      {Href: "/api/v1"},
+			"shipments": {Href: "5/shipments?carrier=ups"},
+		},
+	})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/hal+json")
+	suite.assertBody(w, `{"_links":{`+
+		`"customer":{"href":"/api/v1/customers/7","title":"Bob"},`+
+		`"external":{"href":"https://example.com/docs","type":"text/html"},`+
+		`"items":{"href":"/api/v1/orders/5/items{?page,size}","templated":true},`+
+		`"root":{"href":"/api/v1"},`+
+		`"search":{"href":"/api/v1/orders/5{?q}","templated":true},`+
+		`"self":{"href":"/api/v1/orders/5?expand=true"},`+
+		`"shipments":{"href":"/api/v1/orders/5/shipments?carrier=ups"}},`+
+		`"id":5,"name":"Bob"}`)
+}
+
+func (suite RespondSuite) TestHAL_embedded() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/orders/5")).Ok(&respond.HAL{
+		Value: halOrder{ID: "5", Total: 30, Secret: "shh"},
+		Links: map[string]respond.HALLink{"self": {Href: ""}},
+		Embedded: map[string]interface{}{
+			"items":   []halItem{{SKU: "a"}, {SKU: "b"}},
+			"related": []halOrder{{ID: "6", Secret: "shh"}},
+			"missing": (*halOrder)(nil),
+		},
+	})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/hal+json")
+	suite.assertBody(w, `{"_links":{"self":{"href":"/orders/5"}},`+
+		`"_embedded":{"items":[{"sku":"a"},{"sku":"b"}],"missing":null,`+
+		`"related":[{"_links":{"self":{"href":"/orders/6"}},"id":"6","total":0}]},`+
+		`"id":"5","total":30}`)
+}
+
+// Types can implement HALResource themselves rather than using the wrapper.
+func (suite RespondSuite) TestHAL_interface() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/orders/")).Ok(halOrder{ID: "5", Total: 30, Secret: "shh"})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/hal+json")
+	suite.assertBody(w, `{"_links":{"self":{"href":"/orders/5"}},"id":"5","total":30}`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Created(halOrder{ID: "5"})
+	suite.assertStatus(w, 201)
+	suite.assertBody(w, `{"_links":{"self":{"href":"5"}},"id":"5","total":0}`)
+}
+
+func (suite RespondSuite) TestHAL_notObject() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/orders")).Ok(respond.HAL{Links: map[string]respond.HALLink{"self": {Href: ""}}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"_links":{"self":{"href":"/orders"}}}`)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/orders")).Ok(respond.HAL{Value: map[string]int{}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{}`)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/orders")).Ok(respond.HAL{Value: []string{"a"}})
+	suite.assertStatus(w, 500)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/orders")).Ok((*respond.HAL)(nil))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/json")
+	suite.assertBody(w, `null`)
+}
+
+func (suite RespondSuite) TestHAL_sparseFields() {
+	factory := respond.NewFactory(respond.WithSparseFields(""))
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/orders/?fields=total")).Ok(halOrder{ID: "5", Total: 30})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"_links":{"self":{"href":"/orders/5"}},"total":30}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/orders/?fields=secret")).Ok(halOrder{ID: "5", Total: 30})
+	suite.assertError(w, 400, "unknown field: secret")
+}
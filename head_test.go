@@ -0,0 +1,96 @@
+package respond_test
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/monadicstack/respond"
+)
+
+// respondGetAndHead runs the same responder logic for both a GET and HEAD request so that
+// we can compare the results.
+func (suite RespondSuite) respondGetAndHead(reply func(respond.Responder)) (*mockResponseWriter, *mockResponseWriter) {
+	get := newResponseWriter()
+	reply(respond.To(get, httptest.NewRequest(http.MethodGet, "/foo", nil)))
+
+	head := newResponseWriter()
+	reply(respond.To(head, httptest.NewRequest(http.MethodHead, "/foo", nil)))
+
+	suite.Require().Equal(get.StatusCode, head.StatusCode)
+	suite.Require().Equal(get.Headers, head.Headers)
+	suite.assertEmptyBody(head)
+	return get, head
+}
+
+func (suite RespondSuite) TestHead_json() {
+	get, head := suite.respondGetAndHead(func(r respond.Responder) {
+		r.Ok(mockUser{ID: 42, Name: "Bob"})
+	})
+	suite.assertHeader(head, "Content-Type", "application/json")
+	suite.assertHeader(head, "Content-Length", "22")
+	suite.assertBody(get, `{"id":42,"name":"Bob"}`)
+}
+
+func (suite RespondSuite) TestHead_fail() {
+	_, head := suite.respondGetAndHead(func(r respond.Responder) {
+		r.NotFound("nope")
+	})
+	suite.assertStatus(head, 404)
+	suite.assertHeader(head, "Content-Length", "31")
+}
+
+func (suite RespondSuite) TestHead_html() {
+	_, head := suite.respondGetAndHead(func(r respond.Responder) {
+		r.HTML("<p>hello</p>")
+	})
+	suite.assertHeader(head, "Content-Type", "text/html; charset=utf-8")
+	suite.assertHeader(head, "Content-Length", "12")
+}
+
+// The template should never even be evaluated for HEAD requests.
+func (suite RespondSuite) TestHead_htmlTemplate() {
+	executions := 0
+	temp := template.Must(template.New("HTMLTemplate").Funcs(template.FuncMap{
+		"track": func() string { executions++; return "" },
+	}).Parse(`<p>{{ track }}{{ . }}</p>`))
+
+	get, _ := suite.respondGetAndHead(func(r respond.Responder) {
+		r.HTMLTemplate(temp, "hello")
+	})
+	suite.assertBody(get, "<p>hello</p>")
+	suite.Require().Equal(1, executions)
+}
+
+// We shouldn't read any of the file's data for a HEAD request.
+func (suite RespondSuite) TestHead_serve() {
+	reader := &countingReader{reader: strings.NewReader("hello world")}
+	head := newResponseWriter()
+	respond.To(head, httptest.NewRequest(http.MethodHead, "/foo", nil)).Serve("foo.txt", reader)
+	suite.assertStatus(head, 200)
+	suite.assertHeader(head, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertEmptyBody(head)
+	suite.Require().Equal(0, reader.reads)
+
+	suite.respondGetAndHead(func(r respond.Responder) {
+		r.ServeBytes("foo.txt", []byte("hello world"))
+	})
+	suite.respondGetAndHead(func(r respond.Responder) {
+		r.Download("foo.txt", strings.NewReader("hello world"))
+	})
+	suite.respondGetAndHead(func(r respond.Responder) {
+		r.Ok(sizedContent{data: "hello world"})
+	})
+}
+
+type countingReader struct {
+	reader io.Reader
+	reads  int
+}
+
+func (r *countingReader) Read(buf []byte) (int, error) {
+	r.reads++
+	return r.reader.Read(buf)
+}
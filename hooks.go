@@ -0,0 +1,162 @@
+package respond
+
+import (
+	"net/http"
+	"time"
+)
+
+// WriteEvent describes a response that one of a factory's responders is writing. Before-write hooks
+// receive the status, value, and headers we're about to write, and after-write hooks also receive
+// the details about how the write went.
+type WriteEvent struct {
+	// Request is the HTTP request we're responding to. This may be nil if you created the responder
+	// w/o a request.
+	Request *http.Request
+	// Header contains the response headers. Before-write hooks can modify these; changes made by
+	// after-write hooks are too late to be sent to the caller.
+	Header http.Header
+	// Status is the HTTP status code of the response. For after-write hooks, this is the status that
+	// was actually sent, which may differ from the intended one (e.g. a 206 or 304 when serving a file).
+	Status int
+	// Value is what you're responding with; the value to marshal as JSON, the io.Reader/ContentReader
+	// w/ raw content, the HTML markup or template context, the redirect URL, and so on. It's nil
+	// for responses w/o a body such as NoContent().
+	Value interface{}
+	// BytesWritten is the number of body bytes we wrote. Only available to after-write hooks.
+	BytesWritten int64
+	// Duration is how long it took to write the response. Only available to after-write hooks.
+	Duration time.Duration
+	// Failure is the original error you failed with when this is an error response (i.e. what you
+	// passed to Fail()). It's nil for successful responses.
+	Failure error
+	// Err is the error that occurred while writing the response, if any. Only available to after-write hooks.
+	Err error
+}
+
+// BeforeWriteHook is a function the factory's responders call right before writing the status
+// code and body of every response.
+type BeforeWriteHook func(event WriteEvent)
+
+// AfterWriteHook is a function the factory's responders call right after writing every response.
+type AfterWriteHook func(event WriteEvent)
+
+// FailHook is a function the factory's responders call when they fail w/ an error. It receives
+// the original error you supplied, not the flattened status/message we send back to the caller.
+type FailHook func(req *http.Request, err error)
+
+// WithBeforeWrite registers a hook that runs right before the factory's responders write the
+// status and body of any response. It's the place to add/modify headers based on the response.
+// You can register multiple hooks, and they run in the order you registered them.
+func WithBeforeWrite(hook BeforeWriteHook) FactoryOption {
+	return func(factory *Factory) {
+		factory.beforeWrite = append(factory.beforeWrite, hook)
+	}
+}
+
+// WithAfterWrite registers a hook that runs right after the factory's responders write any
+// response, so you can record how many bytes were written and how long it took. You can register
+// multiple hooks, and they run in the order you registered them.
+func WithAfterWrite(hook AfterWriteHook) FactoryOption {
+	return func(factory *Factory) {
+		factory.afterWrite = append(factory.afterWrite, hook)
+	}
+}
+
+// WithOnFail registers a hook that runs whenever the factory's responders fail w/ an error, giving
+// you access to the original error (and its wrapped errors) before we convert it to a 4XX/5XX
+// response. You can register multiple hooks, and they run in the order you registered them.
+func WithOnFail(hook FailHook) FactoryOption {
+	return func(factory *Factory) {
+		factory.onFail = append(factory.onFail, hook)
+	}
+}
+
+// write runs the factory's before/after hooks around the function that actually writes the
+// response's status and body. The function should write to the given writer rather than the
+// responder's so that we can track the status and number of bytes written. This is also where
+// we send any server timings recorded for the request.
+func (r Responder) write(status int, value interface{}, writeFunc func(w http.ResponseWriter) error) error {
+	r.state.written = true
+	timings := r.serverTimings()
+	timings.writeHeader(r.writer.Header())
+	defer timings.writeTrailer(r.writer.Header())
+
+	if len(r.factory.beforeWrite) == 0 && len(r.factory.afterWrite) == 0 {
+		return writeFunc(r.writer)
+	}
+
+	event := WriteEvent{
+		Request: r.request,
+		Header:  r.writer.Header(),
+		Status:  status,
+		Value:   value,
+		Failure: r.failure,
+	}
+	for _, hook := range r.factory.beforeWrite {
+		hook(event)
+	}
+
+	w := &hookWriter{ResponseWriter: r.writer}
+	start := time.Now()
+	event.Err = writeFunc(w)
+	event.Duration = time.Since(start)
+	event.BytesWritten = w.bytesWritten
+	if w.status != 0 {
+		event.Status = w.status
+	}
+	for _, hook := range r.factory.afterWrite {
+		hook(event)
+	}
+	return event.Err
+}
+
+// responseState is shared by every copy of a responder, so we know once it has started writing
+// its response. After that, a failure can't send a second response; it can only run the hooks.
+type responseState struct {
+	written bool
+}
+
+// fail runs the factory's on-fail hooks for the original error.
+func (r Responder) fail(err error) {
+	for _, hook := range r.factory.onFail {
+		hook(r.request, err)
+	}
+}
+
+// hookWriter keeps track of the status code and number of bytes written to the response so
+// that we can report them to after-write hooks.
+type hookWriter struct {
+	http.ResponseWriter
+	status       int
+	bytesWritten int64
+}
+
+// WriteHeader records the status code before writing it to the underlying response.
+func (w *hookWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written to the underlying response.
+func (w *hookWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytesWritten += int64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the caller if the underlying response supports it.
+func (w *hookWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying response writer to http.ResponseController.
+func (w *hookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,223 @@
+package respond_test
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing/iotest"
+
+	"github.com/monadicstack/respond"
+)
+
+// hookRecorder keeps track of all of the events that a factory's hooks received.
+type hookRecorder struct {
+	before []respond.WriteEvent
+	after  []respond.WriteEvent
+	fails  []error
+}
+
+func (rec *hookRecorder) factory(options ...respond.FactoryOption) *respond.Factory {
+	options = append(options,
+		respond.WithBeforeWrite(func(event respond.WriteEvent) {
+			rec.before = append(rec.before, event)
+		}),
+		respond.WithAfterWrite(func(event respond.WriteEvent) {
+			rec.after = append(rec.after, event)
+		}),
+		respond.WithOnFail(func(req *http.Request, err error) {
+			rec.fails = append(rec.fails, err)
+		}),
+	)
+	return respond.NewFactory(options...)
+}
+
+func (suite RespondSuite) assertHooks(rec *hookRecorder, status int, value interface{}, bytesWritten int64) {
+	suite.Require().Len(rec.before, 1)
+	suite.Require().Len(rec.after, 1)
+	suite.Equal(status, rec.before[0].Status)
+	suite.Equal(value, rec.before[0].Value)
+	suite.Equal(status, rec.after[0].Status)
+	suite.Equal(value, rec.after[0].Value)
+	suite.Equal(bytesWritten, rec.after[0].BytesWritten)
+	suite.NoError(rec.after[0].Err)
+	suite.GreaterOrEqual(int64(rec.after[0].Duration), int64(0))
+}
+
+func (suite RespondSuite) TestHooks_json() {
+	rec := &hookRecorder{}
+	w := newResponseWriter()
+	user := mockUser{ID: 123, Name: "Bob"}
+	rec.factory().To(w, newRequest()).Created(user)
+	suite.assertStatus(w, 201)
+	suite.assertHooks(rec, 201, user, int64(len(w.Body)))
+	suite.Len(rec.fails, 0)
+}
+
+// Before-write hooks should be able to see the headers we set and add their own.
+func (suite RespondSuite) TestHooks_beforeWriteHeaders() {
+	var contentType string
+	factory := respond.NewFactory(respond.WithBeforeWrite(func(event respond.WriteEvent) {
+		contentType = event.Header.Get("Content-Type")
+		event.Header.Set("X-Status", fmt.Sprintf("%d", event.Status))
+	}))
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok("hello")
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `"hello"`)
+	suite.Equal("application/json", contentType)
+	suite.assertHeader(w, "X-Status", "200")
+}
+
+// On-fail hooks should see the original error, not the status/message we flattened it to.
+func (suite RespondSuite) TestHooks_fail() {
+	rec := &hookRecorder{}
+	rootErr := errorWithStatus{status: 409, message: "conflict"}
+	err := fmt.Errorf("saving user: %w", rootErr)
+
+	w := newResponseWriter()
+	rec.factory().To(w, newRequest()).Ok("hello", err)
+	suite.assertError(w, 409, "conflict")
+	suite.Require().Len(rec.fails, 1)
+	suite.Same(err, rec.fails[0])
+	suite.True(errors.Is(rec.fails[0], rootErr))
+
+	// The error body is a response, too, so the write hooks should still fire.
+	suite.Require().Len(rec.after, 1)
+	suite.Equal(409, rec.after[0].Status)
+	suite.Equal(int64(len(w.Body)), rec.after[0].BytesWritten)
+}
+
+func (suite RespondSuite) TestHooks_html() {
+	rec := &hookRecorder{}
+	w := newResponseWriter()
+	rec.factory().To(w, newRequest()).HTML("<p>Hello</p>")
+	suite.assertStatus(w, 200)
+	suite.assertHooks(rec, 200, "<p>Hello</p>", 12)
+
+	rec = &hookRecorder{}
+	w = newResponseWriter()
+	temp := template.Must(template.New("hooks").Parse(`<p>{{ . }}</p>`))
+	rec.factory().To(w, newRequest()).HTMLTemplate(temp, "Bob")
+	suite.assertStatus(w, 200)
+	suite.assertHooks(rec, 200, "Bob", 10)
+}
+
+// Template failures should be reported to the after-write hook as well as the on-fail hook. The
+// status has already been sent by then, so we shouldn't try to write a second (error) response.
+func (suite RespondSuite) TestHooks_htmlTemplateError() {
+	rec := &hookRecorder{}
+	w := newResponseWriter()
+	temp := template.Must(template.New("hooks").Parse(`<p>{{ .Missing }}</p>`))
+	rec.factory().To(w, newRequest()).HTMLTemplate(temp, "Bob")
+	suite.assertStatus(w, 200)
+	suite.NotContains(string(w.Body), "status")
+	suite.Require().Len(rec.before, 1)
+	suite.Require().Len(rec.after, 1)
+	suite.Equal(200, rec.after[0].Status)
+	suite.Error(rec.after[0].Err)
+	suite.Require().Len(rec.fails, 1)
+	suite.Equal(rec.after[0].Err, rec.fails[0])
+}
+
+// Files that fail partway through being copied shouldn't fire the hooks a second time, either.
+func (suite RespondSuite) TestHooks_serveError() {
+	rec := &hookRecorder{}
+	w := newResponseWriter()
+	data := io.MultiReader(strings.NewReader("hello"), iotest.ErrReader(errors.New("disk on fire")))
+	rec.factory().To(w, newRequest()).Download("hello.txt", data)
+	suite.assertStatus(w, 200)
+	suite.Require().Len(rec.before, 1)
+	suite.Require().Len(rec.after, 1)
+	suite.EqualError(rec.after[0].Err, "disk on fire")
+	suite.Require().Len(rec.fails, 1)
+}
+
+func (suite RespondSuite) TestHooks_serve() {
+	rec := &hookRecorder{}
+	w := newResponseWriter()
+	data := strings.NewReader("hello world")
+	rec.factory().To(w, newRequest()).Serve("hello.txt", data)
+	suite.assertStatus(w, 200)
+	suite.assertHooks(rec, 200, data, 11)
+
+	rec = &hookRecorder{}
+	w = newResponseWriter()
+	raw := rawContentReader{reader: newRawString("hello world")}
+	rec.factory().To(w, newRequest()).Ok(raw)
+	suite.assertStatus(w, 200)
+	suite.assertHooks(rec, 200, raw, 11)
+}
+
+// After-write hooks should see the status that was actually sent, such as a 206 for a Range request.
+func (suite RespondSuite) TestHooks_serveRange() {
+	rec := &hookRecorder{}
+	req := httptest.NewRequest(http.MethodGet, "/hello.txt", nil)
+	req.Header.Set("Range", "bytes=0-4")
+
+	w := newResponseWriter()
+	rec.factory().To(w, req).DownloadBytes("hello.txt", []byte("hello world"))
+	suite.assertStatus(w, 206)
+	suite.assertBody(w, "hello")
+	suite.Equal(200, rec.before[0].Status)
+	suite.Equal(206, rec.after[0].Status)
+	suite.Equal(int64(5), rec.after[0].BytesWritten)
+}
+
+func (suite RespondSuite) TestHooks_redirect() {
+	rec := &hookRecorder{}
+	w := newResponseWriter()
+	rec.factory().To(w, newPolicyRequest()).RedirectSeeOther("/users/123")
+	suite.assertStatus(w, 303)
+	suite.Require().Len(rec.after, 1)
+	suite.Equal(303, rec.before[0].Status)
+	suite.Equal("/users/123", rec.before[0].Value)
+	suite.Equal(303, rec.after[0].Status)
+}
+
+func (suite RespondSuite) TestHooks_noContent() {
+	rec := &hookRecorder{}
+	w := newResponseWriter()
+	rec.factory().To(w, newRequest()).NoContent()
+	suite.assertStatus(w, 204)
+	suite.assertHooks(rec, 204, nil, 0)
+
+	rec = &hookRecorder{}
+	w = newResponseWriter()
+	rec.factory().To(w, newRequest()).NotModified()
+	suite.assertStatus(w, 304)
+	suite.assertHooks(rec, 304, nil, 0)
+}
+
+// The file server's HTML error pages should fire the hooks like any other failure.
+func (suite RespondSuite) TestHooks_fileServerErrorPage() {
+	rec := &hookRecorder{}
+	handler := respond.FileServer(newTestFS(), respond.FileServerOptions{Factory: rec.factory()})
+	req := httptest.NewRequest(http.MethodGet, "/missing.txt", nil)
+	req.Header.Set("Accept", "text/html")
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, req)
+	suite.assertStatus(w, 404)
+	suite.Require().Len(rec.fails, 1)
+	suite.Require().Len(rec.after, 1)
+	suite.Equal(404, rec.after[0].Status)
+	suite.Equal(int64(len(w.Body)), rec.after[0].BytesWritten)
+}
+
+// Hooks run in the order you registered them.
+func (suite RespondSuite) TestHooks_order() {
+	var calls []string
+	factory := respond.NewFactory(
+		respond.WithAfterWrite(func(event respond.WriteEvent) { calls = append(calls, "after1") }),
+		respond.WithBeforeWrite(func(event respond.WriteEvent) { calls = append(calls, "before1") }),
+		respond.WithBeforeWrite(func(event respond.WriteEvent) { calls = append(calls, "before2") }),
+		respond.WithAfterWrite(func(event respond.WriteEvent) { calls = append(calls, "after2") }),
+	)
+	factory.To(newResponseWriter(), newRequest()).NoContent()
+	suite.Equal([]string{"before1", "before2", "after1", "after2"}, calls)
+}
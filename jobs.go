@@ -0,0 +1,186 @@
+package respond
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JobState describes where a long-running, asynchronous operation is in its lifecycle.
+type JobState string
+
+const (
+	// JobPending indicates that the job has been accepted but no work has started on it yet.
+	JobPending = JobState("pending")
+	// JobRunning indicates that the job is currently being worked on.
+	JobRunning = JobState("running")
+	// JobSucceeded indicates that the job finished and its result is available at the job's ResultURL.
+	JobSucceeded = JobState("succeeded")
+	// JobFailed indicates that the job finished, but it did not complete successfully.
+	JobFailed = JobState("failed")
+)
+
+// Job describes a long-running operation that you accepted with a 202 and that the caller
+// can poll using a status monitor URL. The typical flow looks like this:
+//
+//   - Your handler kicks off the work and responds with AcceptedJob(). The caller gets a 202 and
+//     a "Location" header pointing at the job's MonitorURL.
+//   - The caller polls the monitor URL and your handler responds with JobStatus(). While the job is
+//     still in flight, the caller gets a 200 with the job's details. Once the job succeeds, the caller
+//     gets a 303 See Other that points at the job's ResultURL.
+type Job struct {
+	// ID is the unique identifier for this job.
+	ID string `json:"id"`
+	// State indicates where the job is in its lifecycle (pending, running, etc).
+	State JobState `json:"state"`
+	// Progress is an optional percentage (0-100) that indicates how close to done the job is.
+	Progress int `json:"progress,omitempty"`
+	// Message is an optional, human-readable description of what the job is currently doing
+	// or why the job failed.
+	Message string `json:"message,omitempty"`
+	// MonitorURL is the location of the status monitor that callers can poll to see how
+	// the job is progressing. This is what we put in the "Location" header of the 202.
+	MonitorURL string `json:"monitorUrl,omitempty"`
+	// ResultURL is the location of the resource created/modified by this job. Once the job
+	// succeeds, the status monitor will redirect callers here using a 303 See Other.
+	ResultURL string `json:"resultUrl,omitempty"`
+	// RetryAfter is how long you'd like callers to wait before polling the status monitor
+	// again. When this is non-zero we'll include a "Retry-After" header in the response.
+	RetryAfter time.Duration `json:"-"`
+	// CreatedAt is the timestamp when the job was first accepted.
+	CreatedAt time.Time `json:"createdAt"`
+	// UpdatedAt is the timestamp when the job's state/progress was last modified.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Done returns true when the job has either succeeded or failed; there's no more work to do.
+func (job Job) Done() bool {
+	return job.State == JobSucceeded || job.State == JobFailed
+}
+
+// AcceptedJob writes a 202 style response to the caller indicating that the long-running job
+// has been accepted. The response includes a "Location" header pointing at the job's MonitorURL,
+// a "Retry-After" header when the job has a RetryAfter value, and the job's details as JSON. If
+// you provided an error, we'll ignore the job and return the appropriate 4XX/5XX response instead.
+func (r Responder) AcceptedJob(job Job, errs ...error) {
+	if err := firstError(errs...); err != nil {
+		r.Fail(err)
+		return
+	}
+	if job.MonitorURL == "" {
+		r.Fail(fmt.Errorf("unable to accept job without a monitor url"))
+		return
+	}
+
+	r.writer.Header().Set("Location", job.MonitorURL)
+	writeRetryAfter(r.writer, job.RetryAfter)
+	r.writeJSON(http.StatusAccepted, job)
+}
+
+// JobStatus is the responder for your job's status monitor. When the job has succeeded
+// and has a ResultURL, this responds with a 303 See Other redirecting the caller to the
+// result. In all other cases (pending, running, failed) this responds with a 200 and the
+// job's details as JSON so the caller can see how things are going. If you provided an
+// error, we'll ignore the job and return the appropriate 4XX/5XX response instead.
+//
+// This pairs nicely with a JobStore: `response.JobStatus(store.Load(ctx, jobID))`
+func (r Responder) JobStatus(job Job, errs ...error) {
+	if err := firstError(errs...); err != nil {
+		r.Fail(err)
+		return
+	}
+
+	if job.State == JobSucceeded && job.ResultURL != "" {
+		r.redirect(http.StatusSeeOther, job.ResultURL)
+		return
+	}
+	if !job.Done() {
+		writeRetryAfter(r.writer, job.RetryAfter)
+	}
+	r.writeJSON(http.StatusOK, job)
+}
+
+// writeRetryAfter applies the "Retry-After" header, rounding the duration up to the
+// nearest whole second. This does nothing if the duration is not positive.
+func writeRetryAfter(res http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		return
+	}
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	res.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// JobStore is the persistence layer for your asynchronous jobs. Your handler that kicks
+// off the work saves the job, your workers update it as they make progress, and your
+// status monitor handler loads it to respond to the caller.
+type JobStore interface {
+	// Save creates or overwrites the job with the given job's ID.
+	Save(ctx context.Context, job Job) error
+	// Load fetches the job with the given ID. When there is no such job, this should
+	// return an error that results in a 404 (e.g. one with a StatusCode() function).
+	Load(ctx context.Context, id string) (Job, error)
+	// Delete removes the job with the given ID. Deleting a job that doesn't exist is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// NewMemoryJobStore creates a JobStore that keeps all of your jobs in memory. This is
+// great for tests and single-instance services, but the jobs will not survive a restart
+// nor will they be visible to other instances of your service.
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: map[string]Job{}}
+}
+
+// MemoryJobStore is a JobStore that keeps all of your jobs in a map that is safe for
+// concurrent use. Use NewMemoryJobStore() to create one.
+type MemoryJobStore struct {
+	mutex sync.RWMutex
+	jobs  map[string]Job
+}
+
+// Save creates or overwrites the job with the given job's ID. This will automatically
+// fill in the CreatedAt/UpdatedAt timestamps if you haven't already.
+func (store *MemoryJobStore) Save(_ context.Context, job Job) error {
+	if job.ID == "" {
+		return errorResponse{Status: http.StatusBadRequest, Message: "unable to save job without an id"}
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	if existing, ok := store.jobs[job.ID]; ok && job.CreatedAt.IsZero() {
+		job.CreatedAt = existing.CreatedAt
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = now
+	}
+	job.UpdatedAt = now
+	store.jobs[job.ID] = job
+	return nil
+}
+
+// Load fetches the job with the given ID. When there is no such job, this returns an
+// error that results in a 404 when passed to your responder.
+func (store *MemoryJobStore) Load(_ context.Context, id string) (Job, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	job, ok := store.jobs[id]
+	if !ok {
+		return Job{}, errorResponse{Status: http.StatusNotFound, Message: "job not found: " + id}
+	}
+	return job, nil
+}
+
+// Delete removes the job with the given ID.
+func (store *MemoryJobStore) Delete(_ context.Context, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.jobs, id)
+	return nil
+}
@@ -0,0 +1,172 @@
+package respond_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+func (suite RespondSuite) TestAcceptedJob() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).AcceptedJob(respond.Job{
+		ID:         "123",
+		State:      respond.JobPending,
+		MonitorURL: "/jobs/123",
+		RetryAfter: 1500 * time.Millisecond,
+	})
+	suite.assertStatus(w, 202)
+	suite.assertHeader(w, "Content-Type", "application/json")
+	suite.assertHeader(w, "Location", "/jobs/123")
+	suite.assertHeader(w, "Retry-After", "2")
+	suite.assertJSON(w, "id", "123")
+	suite.assertJSON(w, "state", "pending")
+	suite.assertJSON(w, "monitorUrl", "/jobs/123")
+}
+
+func (suite RespondSuite) TestAcceptedJob_noRetryAfter() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).AcceptedJob(respond.Job{ID: "123", MonitorURL: "/jobs/123"})
+	suite.assertStatus(w, 202)
+	suite.assertHeader(w, "Location", "/jobs/123")
+	suite.assertHeader(w, "Retry-After", "")
+}
+
+// You can't accept a job without telling the caller where to monitor it.
+func (suite RespondSuite) TestAcceptedJob_noMonitor() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).AcceptedJob(respond.Job{ID: "123"})
+	suite.assertStatus(w, 500)
+	suite.assertHeader(w, "Location", "")
+}
+
+func (suite RespondSuite) TestAcceptedJob_error() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).AcceptedJob(respond.Job{ID: "123", MonitorURL: "/jobs/123"}, errorWithStatus{
+		status:  409,
+		message: "already running",
+	})
+	suite.assertError(w, 409, "already running")
+	suite.assertHeader(w, "Location", "")
+}
+
+func (suite RespondSuite) TestJobStatus_pending() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).JobStatus(respond.Job{
+		ID:         "123",
+		State:      respond.JobRunning,
+		Progress:   42,
+		ResultURL:  "/widgets/1",
+		RetryAfter: 5 * time.Second,
+	})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/json")
+	suite.assertHeader(w, "Location", "")
+	suite.assertHeader(w, "Retry-After", "5")
+	suite.assertJSON(w, "state", "running")
+	suite.assertJSON(w, "progress", 42)
+}
+
+func (suite RespondSuite) TestJobStatus_succeeded() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).JobStatus(respond.Job{
+		ID:         "123",
+		State:      respond.JobSucceeded,
+		ResultURL:  "https://example.com/widgets/1",
+		RetryAfter: 5 * time.Second,
+	})
+	suite.assertStatus(w, 303)
+	suite.assertHeader(w, "Location", "https://example.com/widgets/1")
+	suite.assertHeader(w, "Retry-After", "")
+}
+
+// A successful job w/ no result has nowhere to redirect, so just show the final status.
+func (suite RespondSuite) TestJobStatus_succeededNoResult() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).JobStatus(respond.Job{ID: "123", State: respond.JobSucceeded})
+	suite.assertStatus(w, 200)
+	suite.assertJSON(w, "state", "succeeded")
+}
+
+// The monitor itself worked, so a failed job is still a 200; the body tells you what went wrong.
+func (suite RespondSuite) TestJobStatus_failed() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).JobStatus(respond.Job{
+		ID:         "123",
+		State:      respond.JobFailed,
+		Message:    "out of widgets",
+		ResultURL:  "/widgets/1",
+		RetryAfter: 5 * time.Second,
+	})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Location", "")
+	suite.assertHeader(w, "Retry-After", "")
+	suite.assertJSON(w, "state", "failed")
+	suite.assertJSON(w, "message", "out of widgets")
+}
+
+func (suite RespondSuite) TestJobStatus_error() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	respond.To(w, req).JobStatus(respond.Job{ID: "123"}, fmt.Errorf("crap"))
+	suite.assertError(w, 500, "crap")
+}
+
+func (suite RespondSuite) TestMemoryJobStore() {
+	ctx := context.Background()
+	store := respond.NewMemoryJobStore()
+
+	suite.Require().NoError(store.Save(ctx, respond.Job{ID: "123", State: respond.JobPending}))
+	job, err := store.Load(ctx, "123")
+	suite.Require().NoError(err)
+	suite.Require().Equal("123", job.ID)
+	suite.Require().Equal(respond.JobPending, job.State)
+	suite.Require().False(job.CreatedAt.IsZero())
+	createdAt := job.CreatedAt
+
+	job.State = respond.JobRunning
+	job.CreatedAt = time.Time{}
+	suite.Require().NoError(store.Save(ctx, job))
+	job, err = store.Load(ctx, "123")
+	suite.Require().NoError(err)
+	suite.Require().Equal(respond.JobRunning, job.State)
+	suite.Require().Equal(createdAt, job.CreatedAt)
+
+	suite.Require().NoError(store.Delete(ctx, "123"))
+	suite.Require().NoError(store.Delete(ctx, "123"))
+	_, err = store.Load(ctx, "123")
+	suite.Require().Error(err)
+}
+
+func (suite RespondSuite) TestMemoryJobStore_missingID() {
+	store := respond.NewMemoryJobStore()
+	suite.Require().Error(store.Save(context.Background(), respond.Job{}))
+}
+
+// Loading a job that doesn't exist should naturally result in a 404 from the status monitor.
+func (suite RespondSuite) TestMemoryJobStore_notFound() {
+	w := newResponseWriter()
+	req := newRequest()
+
+	store := respond.NewMemoryJobStore()
+	respond.To(w, req).JobStatus(store.Load(context.Background(), "nope"))
+	suite.assertError(w, 404, "job not found: nope")
+}
@@ -0,0 +1,433 @@
+package respond
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSONAPIContentType is the media type of JSON:API documents.
+const JSONAPIContentType = "application/vnd.api+json"
+
+// WithJSONAPI formats the JSON responses of the factory's responders as JSON:API documents
+// (https://jsonapi.org) w/ the "application/vnd.api+json" content type. Describe your resources
+// using "jsonapi" struct tags:
+//
+//	type Article struct {
+//	    ID       string    `jsonapi:"primary,articles"`
+//	    Title    string    `jsonapi:"attr,title"`
+//	    Body     string    `jsonapi:"attr,body,omitempty"`
+//	    Author   *Person   `jsonapi:"relation,author"`
+//	    Comments []Comment `jsonapi:"relation,comments"`
+//	}
+//
+// Resources become the document's "data", and callers can ask for related resources to be added
+// to "included" using the "include" query parameter (e.g. "?include=author,comments.author"). Values
+// that aren't resources (i.e. they have no "primary" tag) become the document's "meta" instead. Errors
+// from Fail() become JSON:API "errors" arrays, and pages get "links" and "meta" blocks.
+func WithJSONAPI() FactoryOption {
+	return func(factory *Factory) {
+		factory.jsonAPI = true
+	}
+}
+
+// writeJSONAPI writes the value as a JSON:API document. The links and meta are the extra top-level
+// "links" and "meta" for the document, such as the ones describing a page of results.
+func (r Responder) writeJSONAPI(status int, value interface{}, links []pageLink, meta interface{}) {
+	include, err := r.requestedIncludes()
+	if err != nil {
+		r.Fail(err)
+		return
+	}
+
+	builder := jsonAPIBuilder{view: r.View(), seen: map[string]bool{}}
+	document, err := builder.document(value, include)
+	if err != nil {
+		r.Fail(err)
+		return
+	}
+
+	var documentLinks jsonObject
+	if r.request != nil && r.request.URL != nil {
+		documentLinks = append(documentLinks, jsonMember{name: "self", value: r.request.URL.RequestURI()})
+	}
+	for _, link := range links {
+		documentLinks = append(documentLinks, jsonMember{name: link.rel, value: link.uri})
+	}
+	if len(documentLinks) > 0 {
+		document = append(document, jsonMember{name: "links", value: documentLinks})
+	}
+	if meta != nil {
+		document = append(document, jsonMember{name: "meta", value: meta})
+	}
+	r.writeBody(status, JSONAPIContentType, value, document, nil)
+}
+
+// requestedIncludes parses the relationship paths in the "include" query parameter.
+func (r Responder) requestedIncludes() (fieldTree, error) {
+	if r.request == nil || r.request.URL == nil {
+		return nil, nil
+	}
+	param := r.request.URL.Query().Get("include")
+	if param == "" {
+		return nil, nil
+	}
+	include, err := parseFields(param)
+	if err != nil {
+		return nil, errorResponse{Status: http.StatusBadRequest, Message: "invalid include: " + param}
+	}
+	return include, nil
+}
+
+// jsonAPIError is a single error object in a JSON:API "errors" array.
+type jsonAPIError struct {
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Title  string `json:"title,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// jsonAPIErrors is the JSON:API document for an error response.
+type jsonAPIErrors struct {
+	Errors []jsonAPIError `json:"errors"`
+}
+
+// newJSONAPIErrors converts the status/message that we extracted from an error into a JSON:API document.
+func newJSONAPIErrors(errResponse errorResponse) jsonAPIErrors {
+	return jsonAPIErrors{
+		Errors: []jsonAPIError{{
+			ID:     errResponse.RequestID,
+			Status: strconv.Itoa(errResponse.Status),
+			Title:  http.StatusText(errResponse.Status),
+			Detail: errResponse.Message,
+		}},
+	}
+}
+
+// jsonAPIBuilder builds a single JSON:API document, keeping track of the resources that are already
+// part of it so that each one appears only once.
+type jsonAPIBuilder struct {
+	view     string
+	seen     map[string]bool
+	included []interface{}
+}
+
+// document builds the top-level "data" and "included" members of the document for the value.
+func (builder *jsonAPIBuilder) document(value interface{}, include fieldTree) (jsonObject, error) {
+	resources, many, ok := jsonAPIResources(reflect.ValueOf(value))
+	if !ok {
+		if len(include) > 0 {
+			return nil, errorResponse{Status: http.StatusBadRequest, Message: "unknown relationship: " + include.names()[0]}
+		}
+		if value == nil {
+			return jsonObject{{name: "data", value: nil}}, nil
+		}
+		return jsonObject{{name: "meta", value: value}}, nil
+	}
+
+	for _, resource := range resources {
+		builder.seen[jsonAPIKey(resource)] = true
+	}
+	data := make([]interface{}, len(resources))
+	for i, resource := range resources {
+		object, err := builder.resource(resource)
+		if err != nil {
+			return nil, err
+		}
+		data[i] = object
+	}
+	for _, resource := range resources {
+		if err := builder.include(resource, include, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	document := jsonObject{{name: "data", value: data}}
+	if !many {
+		document[0].value = nil
+		if len(data) > 0 {
+			document[0].value = data[0]
+		}
+	}
+	if len(builder.included) > 0 {
+		document = append(document, jsonMember{name: "included", value: builder.included})
+	}
+	return document, nil
+}
+
+// resource builds the JSON:API resource object (type, id, attributes, relationships) for the struct. It
+// fails if the struct (or one of its related resources) has an invalid "jsonapi" tag.
+func (builder *jsonAPIBuilder) resource(v reflect.Value) (jsonObject, error) {
+	info := jsonAPIResourceOf(v.Type())
+	if info.err != nil {
+		return nil, info.err
+	}
+	resource := jsonObject{
+		{name: "type", value: info.resourceType},
+		{name: "id", value: info.id(v)},
+	}
+
+	var attributes jsonObject
+	for _, field := range info.attributes {
+		fieldValue, ok := field.value(v)
+		if !ok || (!field.redact && !field.visibleTo(builder.view)) {
+			continue
+		}
+		if field.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+		attribute := jsonMember{name: field.name, value: RedactedValue}
+		if field.visibleTo(builder.view) {
+			attribute.value, attribute.empty = valueInterface(fieldValue), field.empty
+		}
+		attributes = append(attributes, attribute)
+	}
+	if len(attributes) > 0 {
+		resource = append(resource, jsonMember{name: "attributes", value: attributes})
+	}
+
+	var relationships jsonObject
+	for _, field := range info.relationships {
+		fieldValue, ok := field.value(v)
+		if !ok || !field.visibleTo(builder.view) {
+			continue
+		}
+		if field.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+		related, many, _ := jsonAPIResources(fieldValue)
+		linkage := make([]interface{}, len(related))
+		for i, relatedResource := range related {
+			if err := jsonAPIResourceOf(relatedResource.Type()).err; err != nil {
+				return nil, err
+			}
+			linkage[i] = jsonAPILinkage(relatedResource)
+		}
+
+		var data interface{} = linkage
+		if !many {
+			data = nil
+			if len(linkage) > 0 {
+				data = linkage[0]
+			}
+		}
+		relationships = append(relationships, jsonMember{name: field.name, value: jsonObject{{name: "data", value: data}}})
+	}
+	if len(relationships) > 0 {
+		resource = append(resource, jsonMember{name: "relationships", value: relationships})
+	}
+	return resource, nil
+}
+
+// include adds the related resources for every relationship path in the tree to the document's
+// "included" resources, returning a 400 error if the resource doesn't have one of the relationships.
+func (builder *jsonAPIBuilder) include(v reflect.Value, include fieldTree, path string) error {
+	info := jsonAPIResourceOf(v.Type())
+	for _, name := range include.names() {
+		i, ok := info.relationshipsByName[name]
+		if !ok || !info.relationships[i].visibleTo(builder.view) {
+			return errorResponse{Status: http.StatusBadRequest, Message: "unknown relationship: " + path + name}
+		}
+		fieldValue, ok := info.relationships[i].value(v)
+		if !ok {
+			continue
+		}
+
+		related, _, _ := jsonAPIResources(fieldValue)
+		for _, relatedResource := range related {
+			if key := jsonAPIKey(relatedResource); !builder.seen[key] {
+				builder.seen[key] = true
+				object, err := builder.resource(relatedResource)
+				if err != nil {
+					return err
+				}
+				builder.included = append(builder.included, object)
+			}
+			if err := builder.include(relatedResource, include[name], path+name+"."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonAPIResources dereferences the value and returns the resource structs it contains. The 'many'
+// result indicates that it's a slice/array of resources rather than a single one, and it's not ok if
+// the value contains something other than resources. A nil pointer is a single resource that's missing.
+func jsonAPIResources(v reflect.Value) (resources []reflect.Value, many bool, ok bool) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil, false, isJSONAPIResourceType(v.Type())
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, false, false
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return []reflect.Value{v}, false, jsonAPIResourceOf(v.Type()) != nil
+	case reflect.Slice, reflect.Array:
+		if !isJSONAPIResourceType(v.Type().Elem()) && v.Type().Elem().Kind() != reflect.Interface {
+			return nil, true, false
+		}
+		resources = make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			element, elementMany, ok := jsonAPIResources(v.Index(i))
+			if !ok || elementMany {
+				return nil, true, false
+			}
+			resources = append(resources, element...)
+		}
+		return resources, true, true
+	default:
+		return nil, false, false
+	}
+}
+
+// jsonAPIKey uniquely identifies the resource within a document.
+func jsonAPIKey(v reflect.Value) string {
+	info := jsonAPIResourceOf(v.Type())
+	return info.resourceType + "/" + info.id(v)
+}
+
+// jsonAPILinkage is the resource identifier object (just the type and id) for the resource.
+func jsonAPILinkage(v reflect.Value) jsonObject {
+	info := jsonAPIResourceOf(v.Type())
+	return jsonObject{
+		{name: "type", value: info.resourceType},
+		{name: "id", value: info.id(v)},
+	}
+}
+
+// jsonAPIID formats the value of a resource's primary field as a string.
+func jsonAPIID(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// jsonAPIResourceInfo is the cached metadata describing a struct's "jsonapi" tags.
+type jsonAPIResourceInfo struct {
+	// resourceType is the JSON:API "type" of the resource.
+	resourceType string
+	// primary is the struct's "primary" field w/ the resource's id.
+	primary *structField
+	// attributes are the fields tagged w/ "attr".
+	attributes []structField
+	// relationships are the fields tagged w/ "relation".
+	relationships []structField
+	// relationshipsByName maps relationship names to their index in 'relationships'.
+	relationshipsByName map[string]int
+	// err describes the first invalid "jsonapi" tag on the struct, if any (e.g. a "primary" w/o a type).
+	err error
+}
+
+// id returns the id of the resource struct, formatted as a string since JSON:API ids are always strings.
+func (info *jsonAPIResourceInfo) id(v reflect.Value) string {
+	if id, ok := info.primary.value(v); ok {
+		return jsonAPIID(id)
+	}
+	return ""
+}
+
+// jsonAPIResourceCache maps struct types to their *jsonAPIResourceInfo (nil if the type isn't a resource).
+var jsonAPIResourceCache sync.Map
+
+// isJSONAPIResourceType determines if the type is a resource struct or a pointer to one.
+func isJSONAPIResourceType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && jsonAPIResourceOf(t) != nil
+}
+
+// jsonAPIResourceOf returns the "jsonapi" tag metadata for the struct type. It returns nil if the
+// struct doesn't have a field tagged w/ "primary", so it's not a resource. Results are cached since
+// a type's fields never change.
+func jsonAPIResourceOf(t reflect.Type) *jsonAPIResourceInfo {
+	if cached, ok := jsonAPIResourceCache.Load(t); ok {
+		return cached.(*jsonAPIResourceInfo)
+	}
+
+	info := &jsonAPIResourceInfo{relationshipsByName: map[string]int{}}
+	collectJSONAPIFields(t, nil, info)
+	if info.primary == nil {
+		info = nil
+	}
+	cached, _ := jsonAPIResourceCache.LoadOrStore(t, info)
+	return cached.(*jsonAPIResourceInfo)
+}
+
+// collectJSONAPIFields adds the struct's "jsonapi" tagged fields to the resource info, including
+// those of untagged embedded structs.
+func collectJSONAPIFields(t reflect.Type, index []int, info *jsonAPIResourceInfo) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		tag, ok := sf.Tag.Lookup("jsonapi")
+		if !ok {
+			fieldType := sf.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if sf.Anonymous && fieldType.Kind() == reflect.Struct {
+				collectJSONAPIFields(fieldType, fieldIndex, info)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+
+		options := strings.Split(tag, ",")
+		field := structField{name: sf.Name, index: fieldIndex, typ: sf.Type}
+		if len(options) > 1 && options[1] != "" {
+			field.name = options[1]
+		}
+		if len(options) > 2 {
+			for _, option := range options[2:] {
+				if option == "omitempty" {
+					field.omitEmpty = true
+				}
+			}
+		}
+		if options[0] == "primary" && (len(options) < 2 || options[1] == "") && info.err == nil {
+			info.err = fmt.Errorf("invalid jsonapi tag on %s.%s: primary requires a resource type (e.g. `jsonapi:\"primary,articles\"`)", t.Name(), sf.Name)
+		}
+		if respondTag, ok := sf.Tag.Lookup("respond"); ok {
+			parseRespondTag(&field, respondTag)
+		}
+
+		switch {
+		case options[0] == "primary" && info.primary == nil:
+			info.primary = &field
+			info.resourceType = field.name
+		case field.omit:
+			continue
+		case options[0] == "attr":
+			info.attributes = append(info.attributes, field)
+		case options[0] == "relation":
+			info.relationshipsByName[field.name] = len(info.relationships)
+			info.relationships = append(info.relationships, field)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package respond_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/monadicstack/respond"
+)
+
+type apiPerson struct {
+	ID    int    `jsonapi:"primary,people"`
+	Name  string `jsonapi:"attr,name"`
+	Email string `jsonapi:"attr,email,omitempty" respond:"view=admin"`
+	Hash  string `jsonapi:"attr,hash" respond:"omit"`
+}
+
+type apiComment struct {
+	ID     string     `jsonapi:"primary,comments"`
+	Body   string     `jsonapi:"attr,body"`
+	Author *apiPerson `jsonapi:"relation,author"`
+}
+
+type apiArticle struct {
+	ID       string       `jsonapi:"primary,articles"`
+	Title    string       `jsonapi:"attr,title"`
+	Tags     []string     `jsonapi:"attr,tags,omitempty"`
+	Author   *apiPerson   `jsonapi:"relation,author"`
+	Comments []apiComment `jsonapi:"relation,comments"`
+	Internal string
+}
+
+func newAPIArticle() apiArticle {
+	bob := &apiPerson{ID: 9, Name: "Bob", Email: "bob@example.com", Hash: "abc"}
+	alice := &apiPerson{ID: 2, Name: "Alice"}
+	return apiArticle{
+		ID:     "1",
+		Title:  "Hello",
+		Author: bob,
+		Comments: []apiComment{
+			{ID: "5", Body: "First!", Author: alice},
+			{ID: "12", Body: "Nice", Author: bob},
+		},
+		Internal: "nope",
+	}
+}
+
+func (suite RespondSuite) TestJSONAPI_single() {
+	factory := respond.NewFactory(respond.WithJSONAPI())
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/articles/1")).Ok(newAPIArticle())
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/vnd.api+json")
+	suite.assertBody(w, `{"data":{"type":"articles","id":"1","attributes":{"title":"Hello"},`+
+		`"relationships":{"author":{"data":{"type":"people","id":"9"}},`+
+		`"comments":{"data":[{"type":"comments","id":"5"},{"type":"comments","id":"12"}]}}},`+
+		`"links":{"self":"/articles/1"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/articles/1")).Ok(&apiArticle{ID: "2", Tags: []string{"go"}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"data":{"type":"articles","id":"2","attributes":{"title":"","tags":["go"]},`+
+		`"relationships":{"author":{"data":null},"comments":{"data":[]}}},"links":{"self":"/articles/1"}}`)
+}
+
+func (suite RespondSuite) TestJSONAPI_collection() {
+	factory := respond.NewFactory(respond.WithJSONAPI())
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/people?sort=name")).Ok([]*apiPerson{{ID: 2, Name: "Alice"}, {ID: 9, Name: "Bob", Email: "bob@example.com"}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"data":[{"type":"people","id":"2","attributes":{"name":"Alice"}},`+
+		`{"type":"people","id":"9","attributes":{"name":"Bob"}}],"links":{"self":"/people?sort=name"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/people")).Ok([]apiPerson{})
+	suite.assertBody(w, `{"data":[],"links":{"self":"/people"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/people")).Ok([]interface{}{apiPerson{ID: 2}, &apiComment{ID: "5"}})
+	suite.assertBody(w, `{"data":[{"type":"people","id":"2","attributes":{"name":""}},`+
+		`{"type":"comments","id":"5","attributes":{"body":""},"relationships":{"author":{"data":null}}}],"links":{"self":"/people"}}`)
+}
+
+// Related resources should only be included once, and never if they're already part of the primary data.
+func (suite RespondSuite) TestJSONAPI_include() {
+	factory := respond.NewFactory(respond.WithJSONAPI())
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/articles/1?include=author,comments.author")).Ok(newAPIArticle())
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"data":{"type":"articles","id":"1","attributes":{"title":"Hello"},`+
+		`"relationships":{"author":{"data":{"type":"people","id":"9"}},`+
+		`"comments":{"data":[{"type":"comments","id":"5"},{"type":"comments","id":"12"}]}}},`+
+		`"included":[{"type":"people","id":"9","attributes":{"name":"Bob"}},`+
+		`{"type":"comments","id":"5","attributes":{"body":"First!"},"relationships":{"author":{"data":{"type":"people","id":"2"}}}},`+
+		`{"type":"people","id":"2","attributes":{"name":"Alice"}},`+
+		`{"type":"comments","id":"12","attributes":{"body":"Nice"},"relationships":{"author":{"data":{"type":"people","id":"9"}}}}],`+
+		`"links":{"self":"/articles/1?include=author,comments.author"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/comments?include=author")).Ok([]apiComment{{ID: "5", Author: &apiPerson{ID: 2}}, {ID: "6", Author: &apiPerson{ID: 2}}, {ID: "7"}})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"data":[{"type":"comments","id":"5","attributes":{"body":""},"relationships":{"author":{"data":{"type":"people","id":"2"}}}},`+
+		`{"type":"comments","id":"6","attributes":{"body":""},"relationships":{"author":{"data":{"type":"people","id":"2"}}}},`+
+		`{"type":"comments","id":"7","attributes":{"body":""},"relationships":{"author":{"data":null}}}],`+
+		`"included":[{"type":"people","id":"2","attributes":{"name":""}}],"links":{"self":"/comments?include=author"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/articles/1?include=comments.likes")).Ok(newAPIArticle())
+	suite.assertStatus(w, 400)
+	suite.assertBody(w, `{"errors":[{"status":"400","title":"Bad Request","detail":"unknown relationship: comments.likes"}]}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/articles/1?include=comments..author")).Ok(newAPIArticle())
+	suite.assertStatus(w, 400)
+	suite.assertBody(w, `{"errors":[{"status":"400","title":"Bad Request","detail":"invalid include: comments..author"}]}`)
+}
+
+// Respond tags should hide attributes in JSON:API documents, too.
+func (suite RespondSuite) TestJSONAPI_views() {
+	w := newResponseWriter()
+	req := newHTTPRequest(http.MethodGet, "/people/9")
+	req = req.WithContext(respond.ContextWithView(context.Background(), "admin"))
+	respond.NewFactory(respond.WithJSONAPI()).To(w, req).Ok(apiPerson{ID: 9, Name: "Bob", Email: "bob@example.com", Hash: "abc"})
+	suite.assertBody(w, `{"data":{"type":"people","id":"9","attributes":{"name":"Bob","email":"bob@example.com"}},"links":{"self":"/people/9"}}`)
+}
+
+func (suite RespondSuite) TestJSONAPI_notResource() {
+	factory := respond.NewFactory(respond.WithJSONAPI())
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/stats")).Ok(map[string]int{"count": 5})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "application/vnd.api+json")
+	suite.assertBody(w, `{"meta":{"count":5},"links":{"self":"/stats"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/stats")).Ok(nil)
+	suite.assertBody(w, `{"data":null,"links":{"self":"/stats"}}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/stats?include=author")).Ok(map[string]int{"count": 5})
+	suite.assertStatus(w, 400)
+	suite.assertBody(w, `{"errors":[{"status":"400","title":"Bad Request","detail":"unknown relationship: author"}]}`)
+}
+
+func (suite RespondSuite) TestJSONAPI_errors() {
+	factory := respond.NewFactory(respond.WithJSONAPI())
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/articles/1")).Ok(nil, errorWithStatus{status: 404, message: "article not found"})
+	suite.assertStatus(w, 404)
+	suite.assertHeader(w, "Content-Type", "application/vnd.api+json")
+	suite.assertBody(w, `{"errors":[{"status":"404","title":"Not Found","detail":"article not found"}]}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/articles/1")).Ok(nil, errors.New("database is down"))
+	suite.assertStatus(w, 500)
+	suite.assertBody(w, `{"errors":[{"status":"500","title":"Internal Server Error","detail":"database is down"}]}`)
+
+	w = newResponseWriter()
+	req := newHTTPRequest(http.MethodGet, "/articles/1", "X-Request-ID", "abc123")
+	respond.NewFactory(respond.WithJSONAPI(), respond.WithRequestID(respond.RequestIDOptions{})).To(w, req).Forbidden("nope")
+	suite.assertStatus(w, 403)
+	suite.assertBody(w, `{"errors":[{"id":"abc123","status":"403","title":"Forbidden","detail":"nope"}]}`)
+}
+
+type apiShortTags struct {
+	ID    string `jsonapi:"primary"`
+	Title string `jsonapi:"attr"`
+}
+
+type apiShortRelation struct {
+	ID     string        `jsonapi:"primary,things"`
+	Parent *apiShortTags `jsonapi:"relation"`
+}
+
+// A "primary" tag w/o a type is a mistake we report rather than guess at. Other tags w/o a name use
+// the field's name, just like json tags do.
+func (suite RespondSuite) TestJSONAPI_shortTags() {
+	factory := respond.NewFactory(respond.WithJSONAPI())
+
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/things/1")).Ok(apiShortTags{ID: "1", Title: "Hello"})
+	suite.assertStatus(w, 500)
+	suite.assertBody(w, `{"errors":[{"status":"500","title":"Internal Server Error",`+
+		`"detail":"invalid jsonapi tag on apiShortTags.ID: primary requires a resource type (e.g. `+"`"+`jsonapi:\"primary,articles\"`+"`"+`)"}]}`)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/things/1")).Ok(apiShortRelation{ID: "1", Parent: &apiShortTags{ID: "2"}})
+	suite.assertStatus(w, 500)
+
+	w = newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/things/1")).Ok(apiShortRelation{ID: "1"})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"data":{"type":"things","id":"1","relationships":{"Parent":{"data":null}}},"links":{"self":"/things/1"}}`)
+}
+
+// Like the rest of our JSON, the links' ampersands are HTML-escaped.
+func (suite RespondSuite) TestJSONAPI_page() {
+	factory := respond.NewFactory(respond.WithJSONAPI())
+
+	people := []apiPerson{{ID: 3, Name: "Carl"}}
+	w := newResponseWriter()
+	factory.To(w, newHTTPRequest(http.MethodGet, "/people?page=2&size=1")).Ok(respond.PageNumber(people, 2, 1, 3))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Total-Count", "3")
+	suite.assertBody(w, `{"data":[{"type":"people","id":"3","attributes":{"name":"Carl"}}],`+
+		`"links":{"self":"/people?page=2\u0026size=1","first":"/people?page=1\u0026size=1","prev":"/people?page=1\u0026size=1",`+
+		`"next":"/people?page=3\u0026size=1","last":"/people?page=3\u0026size=1"},`+
+		`"meta":{"page":2,"pageSize":1,"totalItems":3,"totalPages":3}}`)
+}
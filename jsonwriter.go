@@ -0,0 +1,643 @@
+package respond
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// jsonWriter marshals values as JSON, applying the "respond" tags on their struct fields along the way:
+//
+//	respond:"omit"                 Never marshal the field.
+//	respond:"redact"               Replace the field's value w/ RedactedValue.
+//	respond:"view=admin|support"   Only marshal the field for the "admin" or "support" views.
+//	respond:"redact,view=admin"    Show the value to the "admin" view, but redact it for everyone else.
+//
+// It also replaces nil slices/maps w/ empty ones based on WithEmptyCollections() and the "empty"
+// and "nullable" directives. We only walk the parts of a value that could be affected, based on
+// metadata that we cache per type; everything else (usually the entire value) is handed off to the
+// value's fast path or the factory's encoder as-is, so the only cost for most responses is a map lookup.
+type jsonWriter struct {
+	// view is the view of the request we're responding to.
+	view string
+	// emptyCollections indicates that nil slices/maps should be marshaled as [] and {} rather than null.
+	emptyCollections bool
+	// encoder is the factory's encoder, if it has one. Otherwise, we use encoding/json.
+	encoder Encoder
+	// compact indicates that everything we've written so far is compact JSON, which only encoding/json guarantees.
+	compact bool
+	// depth is how deeply we've walked into the value, so that a cyclic value fails rather than overflowing the stack.
+	depth int
+}
+
+// maxJSONDepth is how deeply we'll walk into a value before assuming that it contains a cycle.
+const maxJSONDepth = 1000
+
+// jsonWriter returns a writer for the responder's view and factory settings.
+func (r Responder) jsonWriter() *jsonWriter {
+	return &jsonWriter{
+		view:             r.View(),
+		emptyCollections: r.factory.emptyCollections,
+		encoder:          r.factory.encoder,
+		compact:          true,
+	}
+}
+
+// needsWalk determines if we need to walk the value ourselves because it (or something nested inside of
+// it) has "respond" tags or a nil slice/map that should be empty. When 'empty' is true, the value itself
+// should be [] or {} if it's a nil slice/map. Types that might contain such things (e.g. an interface{}
+// that could hold a tagged struct) are only walked if the value actually does.
+func (w *jsonWriter) needsWalk(v reflect.Value, empty bool) bool {
+	if !v.IsValid() {
+		return false
+	}
+	return w.needsWalkAs(v, jsonTypeOf(v.Type()), empty)
+}
+
+// needsWalkAs is needsWalk() for a value whose type metadata we already have.
+func (w *jsonWriter) needsWalkAs(v reflect.Value, info *jsonType, empty bool) bool {
+	switch {
+	case empty && info.collection && v.IsNil():
+		return true
+	case info.tagged:
+		return true
+	case info.dynamic || ((w.emptyCollections || empty) && info.collections):
+		return w.scan(v, info, empty)
+	default:
+		return false
+	}
+}
+
+// scan looks inside of the value for anything that needs walking, such as an interface holding a
+// tagged struct or a nil slice that should be empty. Pointers and interfaces pass 'empty' along to
+// the value they hold, so a field's "empty"/"nullable" tag applies to a *[]T, too.
+func (w *jsonWriter) scan(v reflect.Value, info *jsonType, empty bool) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return false
+		}
+		if v.CanInterface() {
+			return w.scanAny(v.Interface(), empty)
+		}
+		return w.needsWalk(v.Elem(), empty)
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return false
+		}
+		return w.needsWalk(v.Elem(), empty)
+
+	case reflect.Slice, reflect.Array:
+		if v.Type() == anySliceType && v.CanInterface() {
+			return w.scanAny(v.Interface(), empty)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if w.needsWalk(v.Index(i), w.emptyCollections) {
+				return true
+			}
+		}
+
+	case reflect.Map:
+		if v.Type() == anyMapType && v.CanInterface() {
+			return w.scanAny(v.Interface(), empty)
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if w.needsWalk(iter.Value(), w.emptyCollections) {
+				return true
+			}
+		}
+
+	case reflect.Struct:
+		for _, field := range info.fields {
+			fieldValue, ok := field.value(v)
+			if ok && w.needsWalkAs(fieldValue, field.info, field.emptyCollections(w.emptyCollections)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	anySliceType = reflect.TypeOf([]interface{}{})
+	anyMapType   = reflect.TypeOf(map[string]interface{}{})
+)
+
+// scanAny is a faster scan() for the values that encoding/json unmarshals into an interface{} (maps,
+// slices, strings, etc), which are common in responses, so we check those w/o reflection.
+func (w *jsonWriter) scanAny(value interface{}, empty bool) bool {
+	switch value := value.(type) {
+	case nil, bool, float64, string, int, int64, json.Number:
+		return false
+	case map[string]interface{}:
+		if value == nil {
+			return empty
+		}
+		for _, element := range value {
+			if w.scanAny(element, w.emptyCollections) {
+				return true
+			}
+		}
+		return false
+	case []interface{}:
+		if value == nil {
+			return empty
+		}
+		for _, element := range value {
+			if w.scanAny(element, w.emptyCollections) {
+				return true
+			}
+		}
+		return false
+	default:
+		return w.needsWalk(reflect.ValueOf(value), empty)
+	}
+}
+
+// append writes the JSON for the value to 'dst'. When 'empty' is true, a nil slice/map is written as
+// [] or {} rather than null.
+func (w *jsonWriter) append(dst []byte, v reflect.Value, empty bool) ([]byte, error) {
+	if !v.IsValid() {
+		return append(dst, "null"...), nil
+	}
+	return w.appendAs(dst, v, jsonTypeOf(v.Type()), empty)
+}
+
+// appendAs is append() for a value whose type metadata we already have.
+func (w *jsonWriter) appendAs(dst []byte, v reflect.Value, info *jsonType, empty bool) ([]byte, error) {
+	if empty && info.collection && v.IsNil() {
+		if v.Kind() == reflect.Map {
+			return append(dst, "{}"...), nil
+		}
+		return append(dst, "[]"...), nil
+	}
+	if !w.needsWalkAs(v, info, empty) {
+		return w.appendValue(dst, v, info)
+	}
+
+	if w.depth++; w.depth > maxJSONDepth {
+		return dst, &json.UnsupportedValueError{Value: v, Str: "encountered a cycle via " + v.Type().String()}
+	}
+	dst, err := w.walk(dst, v, info, empty)
+	w.depth--
+	return dst, err
+}
+
+// walk writes the JSON for a value that needs our tags or empty collections applied somewhere inside of it.
+func (w *jsonWriter) walk(dst []byte, v reflect.Value, info *jsonType, empty bool) ([]byte, error) {
+	switch {
+	case v.Type() == jsonObjectType:
+		return w.appendObject(dst, v.Interface().(jsonObject))
+	case v.Type() == halDocumentType:
+		return w.appendHAL(dst, v.Interface().(halDocument))
+	case info.custom && !(v.Kind() == reflect.Ptr && v.IsNil()):
+		return w.appendValue(dst, v, info)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return append(dst, "null"...), nil
+		}
+		return w.append(dst, v.Elem(), empty)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return append(dst, "null"...), nil
+		}
+		var err error
+		dst = append(dst, '[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			if dst, err = w.append(dst, v.Index(i), w.emptyCollections); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, ']'), nil
+
+	case reflect.Map:
+		return w.appendMap(dst, v)
+
+	case reflect.Struct:
+		return w.appendStruct(dst, v, info)
+
+	default:
+		return w.appendValue(dst, v, info)
+	}
+}
+
+// appendStruct writes the struct's JSON object, minus the fields that the view isn't allowed to see.
+func (w *jsonWriter) appendStruct(dst []byte, v reflect.Value, info *jsonType) ([]byte, error) {
+	var err error
+	dst = append(dst, '{')
+	first := true
+	for _, field := range info.fields {
+		fieldValue, ok := field.value(v)
+		if !ok {
+			continue
+		}
+		visible := field.visibleTo(w.view)
+		if !visible && !field.redact {
+			continue
+		}
+		if field.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		if !first {
+			dst = append(dst, ',')
+		}
+		first = false
+		dst = append(dst, field.key...)
+
+		switch {
+		case !visible:
+			dst = appendJSONString(dst, RedactedValue)
+		case field.quoted:
+			dst, err = w.appendQuoted(dst, fieldValue, field.info)
+		default:
+			dst, err = w.appendAs(dst, fieldValue, field.info, field.emptyCollections(w.emptyCollections))
+		}
+		if err != nil {
+			return dst, err
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// appendQuoted writes the value as a JSON string, like the json tag's "string" option does (nulls stay null).
+func (w *jsonWriter) appendQuoted(dst []byte, v reflect.Value, info *jsonType) ([]byte, error) {
+	start := len(dst)
+	dst, err := w.appendAs(dst, v, info, false)
+	if err != nil || string(dst[start:]) == "null" {
+		return dst, err
+	}
+	return appendJSONString(dst[:start], string(dst[start:])), nil
+}
+
+// jsonMapEntry is a single key/value of a map that we're walking.
+type jsonMapEntry struct {
+	key   string
+	value reflect.Value
+}
+
+// appendMap writes the map's JSON object w/ its keys sorted, just like encoding/json does.
+func (w *jsonWriter) appendMap(dst []byte, v reflect.Value) ([]byte, error) {
+	if v.IsNil() {
+		return append(dst, "null"...), nil
+	}
+
+	entries := make([]jsonMapEntry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := jsonMapKey(iter.Key())
+		if err != nil {
+			return dst, err
+		}
+		entries = append(entries, jsonMapEntry{key: key, value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	var err error
+	dst = append(dst, '{')
+	for i, entry := range entries {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(appendJSONString(dst, entry.key), ':')
+		if dst, err = w.append(dst, entry.value, w.emptyCollections); err != nil {
+			return dst, err
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// jsonMapKey returns the JSON object key that encoding/json uses for the map key.
+func jsonMapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if key.Kind() == reflect.Ptr && key.IsNil() {
+			return "", nil
+		}
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	default:
+		return "", &json.UnsupportedTypeError{Type: key.Type()}
+	}
+}
+
+// appendValue writes the JSON for a value that doesn't need any tags or empty collections applied. We
+// use the value's own fast path (JSONAppender or JSONMarshalerTo) if it has one or the factory's encoder
+// otherwise. W/o an encoder, we write simple values ourselves and leave everything else to encoding/json.
+func (w *jsonWriter) appendValue(dst []byte, v reflect.Value, info *jsonType) ([]byte, error) {
+	if info.custom && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		switch value := valueInterface(v).(type) {
+		case JSONAppender:
+			w.compact = false
+			return value.AppendJSON(dst)
+		case JSONMarshalerTo:
+			w.compact = false
+			buf := bytes.NewBuffer(dst)
+			err := value.MarshalJSONTo(buf)
+			return buf.Bytes(), err
+		}
+	}
+	if w.encoder != nil {
+		w.compact = false
+		jsonBytes, err := w.encoder.Marshal(valueInterface(v))
+		return appendJSON(dst, jsonBytes), err
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if jsonBytes, ok := appendJSONScalar(dst, v, info); ok {
+			return jsonBytes, nil
+		}
+	}
+
+	jsonBytes, err := json.Marshal(valueInterface(v))
+	return appendJSON(dst, jsonBytes), err
+}
+
+// appendJSON adds the marshaled value to 'dst', skipping the copy if we haven't written anything yet.
+func appendJSON(dst []byte, jsonBytes []byte) []byte {
+	if len(dst) == 0 {
+		return jsonBytes
+	}
+	return append(dst, jsonBytes...)
+}
+
+// appendJSONScalar writes a bool, number, or string value the same way that encoding/json does. It
+// returns false if the value is one that we should leave to encoding/json, such as a type w/ custom
+// marshaling or a NaN (which is an error).
+func appendJSONScalar(dst []byte, v reflect.Value, info *jsonType) ([]byte, bool) {
+	if info.custom || v.Type() == jsonNumberType {
+		return dst, false
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.AppendBool(dst, v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(dst, v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(dst, v.Uint(), 10), true
+	case reflect.String:
+		return appendJSONString(dst, v.String()), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return dst, false
+		}
+		return appendJSONFloat(dst, f, v.Type().Bits()), true
+	default:
+		return dst, false
+	}
+}
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// appendJSONFloat formats the float like encoding/json does: w/o an exponent unless it's very small or large.
+func appendJSONFloat(dst []byte, f float64, bits int) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(dst); n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes the quoted JSON string, escaping it the same way that encoding/json does
+// (including HTML characters like "<" and "&").
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			dst = append(append(dst, s[start:i]...), "\ufffd"...)
+		case c == '\u2028' || c == '\u2029':
+			dst = append(append(dst, s[start:i]...), '\\', 'u', '2', '0', '2', hexDigits[c&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	return append(append(dst, s[start:]...), '"')
+}
+
+// valueInterface returns the value as an interface{} that marshals to the same JSON. Like encoding/json,
+// we use pointer-receiver MarshalJSON()/MarshalText() methods when the value is addressable.
+func valueInterface(v reflect.Value) interface{} {
+	if v.Kind() != reflect.Ptr && v.CanAddr() && !v.Type().Implements(jsonMarshalerType) {
+		ptr := v.Addr()
+		if ptr.Type().Implements(jsonMarshalerType) || ptr.Type().Implements(textMarshalerType) {
+			return ptr.Interface()
+		}
+	}
+	return v.Interface()
+}
+
+// jsonType is the metadata that we cache for each type to decide how much of a value we need to walk.
+type jsonType struct {
+	// custom indicates that the type decides for itself how it's marshaled (e.g. it has MarshalJSON()).
+	custom bool
+	// tagged indicates that values can contain struct fields w/ "respond" tags, so we always walk them.
+	tagged bool
+	// collections indicates that values can contain slices/maps (other than themselves) that might be nil.
+	collections bool
+	// dynamic indicates that values can contain interfaces, so we need to look at what they actually hold.
+	dynamic bool
+	// collection indicates that the type itself is a slice/map that we can write as [] or {} when it's nil.
+	collection bool
+	// fields are the plans for writing a struct type's fields, so we only work them out once per type.
+	fields []jsonField
+}
+
+// jsonField is the plan for writing a single struct field.
+type jsonField struct {
+	structField
+	// key is the field's quoted name and colon (e.g. `"name":`), ready to write.
+	key []byte
+	// info is the metadata for the field's type.
+	info *jsonType
+}
+
+// jsonTypeCache maps types to their *jsonType.
+var jsonTypeCache sync.Map
+
+// jsonTypeOf returns the cached metadata for the type.
+func jsonTypeOf(t reflect.Type) *jsonType {
+	if cached, ok := jsonTypeCache.Load(t); ok {
+		return cached.(*jsonType)
+	}
+	info := &jsonType{
+		custom:      hasCustomJSON(t),
+		tagged:      hasTaggedFields(t, map[reflect.Type]bool{}),
+		collections: hasNestedCollections(t, map[reflect.Type]bool{}),
+		dynamic:     hasInterfaces(t, map[reflect.Type]bool{}),
+		collection:  isCollectionType(t),
+	}
+	if t.Kind() == reflect.Struct && !info.custom {
+		// Struct values can't contain themselves (only pointers to themselves), so this always ends.
+		for _, field := range structFields(t).fields {
+			key := append(appendJSONString(nil, field.name), ':')
+			info.fields = append(info.fields, jsonField{structField: field, key: key, info: jsonTypeOf(field.typ)})
+		}
+	}
+	cached, _ := jsonTypeCache.LoadOrStore(t, info)
+	return cached.(*jsonType)
+}
+
+// hasTaggedFields determines if marshaling a value of this type might include struct fields w/ "respond"
+// tags, including those nested inside other structs, slices, maps, and pointers. It keeps track of the
+// types we've already visited so that recursive types (e.g. a tree of nodes) don't recurse forever. The
+// documents we build (e.g. for JSON:API) count since they always need to be walked. So do types w/ a
+// JSON fast path; encoding/json would use their MarshalJSON() instead, so we walk anything that contains
+// them in order to encode them the same way no matter where they are.
+func hasTaggedFields(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	if t == jsonObjectType || t == halDocumentType {
+		return true
+	}
+	if hasCustomJSON(t) {
+		return hasJSONFastPath(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasTaggedFields(t.Elem(), visited)
+	case reflect.Struct:
+		info := structFields(t)
+		if info.tagged {
+			return true
+		}
+		for _, field := range info.fields {
+			if hasTaggedFields(field.typ, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasInterfaces determines if a value of this type can contain an interface, whose value could be
+// anything (including a struct w/ "respond" tags).
+func hasInterfaces(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	if hasCustomJSON(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasInterfaces(t.Elem(), visited)
+	case reflect.Struct:
+		for _, field := range structFields(t).fields {
+			if hasInterfaces(field.typ, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonObject is a JSON object that we build ourselves, such as a JSON:API document or an envelope.
+// We write its members in order.
+type jsonObject []jsonMember
+
+// jsonMember is a single field/value of a jsonObject.
+type jsonMember struct {
+	name  string
+	value interface{}
+	// empty overrides the factory's setting for writing the value as [] or {} if it's a nil slice/map.
+	empty emptyMode
+}
+
+var jsonObjectType = reflect.TypeOf(jsonObject{})
+
+// appendObject writes the object's members in order.
+func (w *jsonWriter) appendObject(dst []byte, object jsonObject) ([]byte, error) {
+	var err error
+	dst = append(dst, '{')
+	for i, member := range object {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(appendJSONString(dst, member.name), ':')
+		if dst, err = w.append(dst, reflect.ValueOf(member.value), member.empty.resolve(w.emptyCollections)); err != nil {
+			return dst, err
+		}
+	}
+	return append(dst, '}'), nil
+}
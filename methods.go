@@ -0,0 +1,113 @@
+package respond
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Methods maps HTTP methods (e.g. http.MethodGet) to the handlers for a single resource. It's an
+// http.Handler, so rather than hand-rolling a `switch req.Method` block that ends with a call to
+// MethodNotAllowed(), you can just register the methods your resource supports:
+//
+//	http.Handle("/users/", respond.Methods{
+//	    http.MethodGet:    GetUser,
+//	    http.MethodPut:    UpdateUser,
+//	    http.MethodDelete: DeleteUser,
+//	})
+//
+// Requests for methods you didn't register fail w/ a standard 405 error and an "Allow" header
+// listing the methods you did. OPTIONS requests are answered automatically with a 204 and the
+// same "Allow" header, and HEAD requests use your GET handler unless you register one explicitly.
+// Method names are case-insensitive when you register them, so "get" works just like "GET".
+type Methods map[string]http.HandlerFunc
+
+// ServeHTTP dispatches the request to the handler registered for the request's method.
+func (methods Methods) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	methods.serveHTTP(defaultFactory, w, req)
+}
+
+// Methods creates an http.Handler that behaves exactly like the Methods type does, except the
+// automatic OPTIONS and 405 responses use this factory's responders.
+func (factory *Factory) Methods(methods Methods) http.Handler {
+	methods = methods.normalize()
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		methods.serveHTTP(factory, w, req)
+	})
+}
+
+// serveHTTP dispatches the request to the handler registered for the request's method, using
+// the factory's responders for the automatic OPTIONS/405 responses.
+func (methods Methods) serveHTTP(factory *Factory, w http.ResponseWriter, req *http.Request) {
+	if handler := methods.handler(req.Method); handler != nil {
+		handler(w, req)
+		return
+	}
+
+	w.Header().Set("Allow", methods.allow())
+	if req.Method == http.MethodOptions {
+		factory.To(w, req).NoContent()
+		return
+	}
+	factory.To(w, req).MethodNotAllowed("method not allowed: %s", req.Method)
+}
+
+// normalize returns a copy of the methods w/ all of the names upper-cased. When you register both
+// "get" and "GET", the upper-case one wins.
+func (methods Methods) normalize() Methods {
+	normalized := make(Methods, len(methods))
+	for method, handler := range methods {
+		if upper := strings.ToUpper(method); upper == method || normalized[upper] == nil {
+			normalized[upper] = handler
+		}
+	}
+	return normalized
+}
+
+// handler finds the handler registered for the given method. HEAD requests fall back to the
+// GET handler if you didn't explicitly register one for HEAD.
+func (methods Methods) handler(method string) http.HandlerFunc {
+	if handler := methods.lookup(method); handler != nil {
+		return handler
+	}
+	if method == http.MethodHead {
+		return methods.lookup(http.MethodGet)
+	}
+	return nil
+}
+
+// lookup finds the handler registered for the method, whether its name was registered in upper-case
+// or not. We only bother comparing names when the exact one isn't there.
+func (methods Methods) lookup(method string) http.HandlerFunc {
+	if handler := methods[method]; handler != nil {
+		return handler
+	}
+	var found http.HandlerFunc
+	for name, handler := range methods {
+		if handler != nil && strings.ToUpper(name) == method {
+			found = handler
+		}
+	}
+	return found
+}
+
+// allow builds the value of the "Allow" header, listing all of the methods that have a handler
+// (plus the ones we handle automatically) in alphabetical order.
+func (methods Methods) allow() string {
+	allowed := map[string]bool{http.MethodOptions: true}
+	for method, handler := range methods {
+		if handler != nil {
+			allowed[strings.ToUpper(method)] = true
+		}
+	}
+	if allowed[http.MethodGet] {
+		allowed[http.MethodHead] = true
+	}
+
+	names := make([]string, 0, len(allowed))
+	for method := range allowed {
+		names = append(names, method)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,106 @@
+package respond_test
+
+import (
+	"net/http"
+
+	"github.com/monadicstack/respond"
+)
+
+func newTestMethods() respond.Methods {
+	return respond.Methods{
+		http.MethodGet: func(w http.ResponseWriter, req *http.Request) {
+			respond.To(w, req).Ok("got it")
+		},
+		http.MethodDelete: func(w http.ResponseWriter, req *http.Request) {
+			respond.To(w, req).NoContent()
+		},
+	}
+}
+
+func (suite RespondSuite) TestMethods_dispatch() {
+	w := newResponseWriter()
+	newTestMethods().ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo"))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `"got it"`)
+	suite.assertHeader(w, "Allow", "")
+
+	w = newResponseWriter()
+	newTestMethods().ServeHTTP(w, newHTTPRequest(http.MethodDelete, "/foo"))
+	suite.assertStatus(w, 204)
+}
+
+// HEAD should use the GET handler unless you register your own.
+func (suite RespondSuite) TestMethods_head() {
+	w := newResponseWriter()
+	newTestMethods().ServeHTTP(w, newHTTPRequest(http.MethodHead, "/foo"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Length", "8")
+	suite.assertEmptyBody(w)
+
+	methods := respond.Methods{
+		http.MethodPost: func(w http.ResponseWriter, req *http.Request) {},
+	}
+	w = newResponseWriter()
+	methods.ServeHTTP(w, newHTTPRequest(http.MethodHead, "/foo"))
+	suite.assertStatus(w, 405)
+	suite.assertHeader(w, "Allow", "OPTIONS, POST")
+	suite.assertEmptyBody(w)
+}
+
+func (suite RespondSuite) TestMethods_notAllowed() {
+	w := newResponseWriter()
+	newTestMethods().ServeHTTP(w, newHTTPRequest(http.MethodPatch, "/foo"))
+	suite.assertError(w, 405, "method not allowed: PATCH")
+	suite.assertHeader(w, "Allow", "DELETE, GET, HEAD, OPTIONS")
+}
+
+func (suite RespondSuite) TestMethods_options() {
+	w := newResponseWriter()
+	newTestMethods().ServeHTTP(w, newHTTPRequest(http.MethodOptions, "/foo"))
+	suite.assertStatus(w, 204)
+	suite.assertHeader(w, "Allow", "DELETE, GET, HEAD, OPTIONS")
+	suite.assertEmptyBody(w)
+
+	// You can still handle OPTIONS yourself if you want.
+	methods := respond.Methods{
+		http.MethodOptions: func(w http.ResponseWriter, req *http.Request) {
+			respond.To(w, req).Ok("custom")
+		},
+	}
+	w = newResponseWriter()
+	methods.ServeHTTP(w, newHTTPRequest(http.MethodOptions, "/foo"))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `"custom"`)
+}
+
+func (suite RespondSuite) TestMethods_factory() {
+	handler := respond.NewFactory().Methods(newTestMethods())
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodPut, "/foo"))
+	suite.assertError(w, 405, "method not allowed: PUT")
+	suite.assertHeader(w, "Allow", "DELETE, GET, HEAD, OPTIONS")
+}
+
+// Method names should work no matter how you capitalize them when registering.
+func (suite RespondSuite) TestMethods_lowerCase() {
+	methods := respond.Methods{
+		"get": func(w http.ResponseWriter, req *http.Request) {
+			respond.To(w, req).Ok("got it")
+		},
+	}
+
+	w := newResponseWriter()
+	methods.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo"))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `"got it"`)
+
+	w = newResponseWriter()
+	respond.NewFactory().Methods(methods).ServeHTTP(w, newHTTPRequest(http.MethodHead, "/foo"))
+	suite.assertStatus(w, 200)
+
+	w = newResponseWriter()
+	methods.ServeHTTP(w, newHTTPRequest(http.MethodPost, "/foo"))
+	suite.assertError(w, 405, "method not allowed: POST")
+	suite.assertHeader(w, "Allow", "GET, HEAD, OPTIONS")
+}
@@ -0,0 +1,313 @@
+package respond
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds (in seconds) of the latency histogram buckets that
+// Metrics uses when you don't supply your own.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds (in bytes) of the response size histogram buckets that
+// Metrics uses when you don't supply your own.
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// unknownRoute is the route label for requests where we couldn't determine the route.
+const unknownRoute = "unknown"
+
+// otherMethod is the method label for requests that don't use one of the standard HTTP methods.
+const otherMethod = "OTHER"
+
+// metricsMethods are the methods we label as-is. Callers can send any method they like, so
+// recording arbitrary ones would let them create as many sets of metrics as they want.
+var metricsMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// MetricsOptions customizes how Metrics labels and buckets the responses it records.
+type MetricsOptions struct {
+	// LatencyBuckets are the upper bounds (in seconds) of the latency histogram buckets. When this
+	// is empty, we use DefaultLatencyBuckets.
+	LatencyBuckets []float64
+	// SizeBuckets are the upper bounds (in bytes) of the response size histogram buckets. When this
+	// is empty, we use DefaultSizeBuckets.
+	SizeBuckets []float64
+	// Route determines the route label for the request. When this is nil, we use the ServeMux pattern
+	// that matched the request (e.g. "GET /users/{id}") on Go 1.22+. We never use the raw path since
+	// every distinct user ID, etc. would create its own set of metrics.
+	Route func(req *http.Request) string
+}
+
+// Metrics records request counts, status classes, latencies, and response sizes for every response
+// written by the factories you register it with. You can expose them to Prometheus using Handler(),
+// and since Metrics is an expvar.Var, you can publish them w/ expvar, too.
+//
+//	metrics := respond.NewMetrics(respond.MetricsOptions{})
+//	responders := respond.NewFactory(respond.WithMetrics(metrics))
+//	expvar.Publish("respond", metrics)
+//	http.Handle("/metrics", metrics.Handler())
+type Metrics struct {
+	options MetricsOptions
+	mutex   sync.Mutex
+	routes  map[string]*routeMetrics
+}
+
+// routeMetrics contains all of the metrics we track for a single route.
+type routeMetrics struct {
+	requests map[statusKey]int64
+	latency  *histogram
+	size     *histogram
+}
+
+// statusKey identifies the request method and status class (e.g. "2xx") of a response.
+type statusKey struct {
+	method      string
+	statusClass string
+}
+
+// histogram tracks how many observations fell into each bucket (non-cumulative) as well as the
+// total sum/count of observations.
+type histogram struct {
+	buckets []float64
+	counts  []int64
+	sum     float64
+	count   int64
+}
+
+// metricsStartContextKey is the context key where the metrics middleware stores the time the request started.
+type metricsStartContextKey struct{}
+
+// NewMetrics creates an empty set of metrics w/ the given customizations.
+func NewMetrics(options MetricsOptions) *Metrics {
+	if len(options.LatencyBuckets) == 0 {
+		options.LatencyBuckets = DefaultLatencyBuckets
+	}
+	if len(options.SizeBuckets) == 0 {
+		options.SizeBuckets = DefaultSizeBuckets
+	}
+	if options.Route == nil {
+		options.Route = routePattern
+	}
+	return &Metrics{
+		options: options,
+		routes:  map[string]*routeMetrics{},
+	}
+}
+
+// WithMetrics records every response written by the factory's responders in the given metrics.
+func WithMetrics(metrics *Metrics) FactoryOption {
+	return WithAfterWrite(metrics.record)
+}
+
+// Middleware records the time each request starts so that the latency histogram includes all of the
+// time your handler took rather than just the time it took to write the response. Unlike most metrics
+// middleware, it doesn't wrap the http.ResponseWriter, so things like http.Flusher keep working.
+func (metrics *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), metricsStartContextKey{}, time.Now())
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// record is the after-write hook that updates the metrics for the response we just wrote.
+func (metrics *Metrics) record(event WriteEvent) {
+	route, method, latency := unknownRoute, "", event.Duration
+	if event.Request != nil {
+		if r := metrics.options.Route(event.Request); r != "" {
+			route = r
+		}
+		method = event.Request.Method
+		if start, ok := event.Request.Context().Value(metricsStartContextKey{}).(time.Time); ok {
+			latency = time.Since(start)
+		}
+	}
+	switch {
+	case method == "":
+		method = http.MethodGet
+	case !metricsMethods[method]:
+		method = otherMethod
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	routeStats, ok := metrics.routes[route]
+	if !ok {
+		routeStats = &routeMetrics{
+			requests: map[statusKey]int64{},
+			latency:  newHistogram(metrics.options.LatencyBuckets),
+			size:     newHistogram(metrics.options.SizeBuckets),
+		}
+		metrics.routes[route] = routeStats
+	}
+	routeStats.requests[statusKey{method: method, statusClass: statusClass(event.Status)}]++
+	routeStats.latency.observe(latency.Seconds())
+	routeStats.size.observe(float64(event.BytesWritten))
+}
+
+// Handler creates an HTTP handler that responds with the metrics in the Prometheus text exposition format.
+func (metrics *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(metrics.prometheus()))
+	})
+}
+
+// String returns a JSON snapshot of the metrics so that you can publish them using expvar.
+func (metrics *Metrics) String() string {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	type histogramJSON struct {
+		Buckets map[string]int64 `json:"buckets"`
+		Sum     float64          `json:"sum"`
+		Count   int64            `json:"count"`
+	}
+	type routeJSON struct {
+		Requests map[string]map[string]int64 `json:"requests"`
+		Latency  histogramJSON               `json:"latencySeconds"`
+		Size     histogramJSON               `json:"sizeBytes"`
+	}
+	toHistogramJSON := func(h *histogram) histogramJSON {
+		buckets := map[string]int64{}
+		h.cumulative(func(le string, count int64) { buckets[le] = count })
+		return histogramJSON{Buckets: buckets, Sum: h.sum, Count: h.count}
+	}
+
+	routes := map[string]routeJSON{}
+	for route, routeStats := range metrics.routes {
+		requests := map[string]map[string]int64{}
+		for key, count := range routeStats.requests {
+			if requests[key.method] == nil {
+				requests[key.method] = map[string]int64{}
+			}
+			requests[key.method][key.statusClass] = count
+		}
+		routes[route] = routeJSON{
+			Requests: requests,
+			Latency:  toHistogramJSON(routeStats.latency),
+			Size:     toHistogramJSON(routeStats.size),
+		}
+	}
+
+	jsonBytes, _ := json.Marshal(map[string]interface{}{"routes": routes})
+	return string(jsonBytes)
+}
+
+// prometheus renders all of the metrics in the Prometheus text exposition format.
+func (metrics *Metrics) prometheus() string {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	routes := make([]string, 0, len(metrics.routes))
+	for route := range metrics.routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	out := &strings.Builder{}
+	out.WriteString("# HELP respond_requests_total Total number of responses written, by route, method, and status class.\n")
+	out.WriteString("# TYPE respond_requests_total counter\n")
+	for _, route := range routes {
+		requests := metrics.routes[route].requests
+		keys := make([]statusKey, 0, len(requests))
+		for key := range requests {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].method != keys[j].method {
+				return keys[i].method < keys[j].method
+			}
+			return keys[i].statusClass < keys[j].statusClass
+		})
+		for _, key := range keys {
+			fmt.Fprintf(out, "respond_requests_total{route=%s,method=%s,status=%s} %d\n",
+				quoteLabel(route),
+				quoteLabel(key.method),
+				quoteLabel(key.statusClass),
+				requests[key],
+			)
+		}
+	}
+
+	out.WriteString("# HELP respond_request_duration_seconds How long it took to respond to requests, by route.\n")
+	out.WriteString("# TYPE respond_request_duration_seconds histogram\n")
+	for _, route := range routes {
+		metrics.routes[route].latency.writePrometheus(out, "respond_request_duration_seconds", route)
+	}
+
+	out.WriteString("# HELP respond_response_size_bytes The size of response bodies, by route.\n")
+	out.WriteString("# TYPE respond_response_size_bytes histogram\n")
+	for _, route := range routes {
+		metrics.routes[route].size.writePrometheus(out, "respond_response_size_bytes", route)
+	}
+	return out.String()
+}
+
+// newHistogram creates an empty histogram w/ the given bucket upper bounds.
+func newHistogram(buckets []float64) *histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &histogram{buckets: sorted, counts: make([]int64, len(sorted))}
+}
+
+// observe adds the value to the first bucket that it fits in (if any) as well as the sum/count.
+func (h *histogram) observe(value float64) {
+	h.sum += value
+	h.count++
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+}
+
+// cumulative supplies the callback w/ the cumulative count for each bucket's upper bound, as well as
+// the "+Inf" bucket containing every observation.
+func (h *histogram) cumulative(callback func(le string, count int64)) {
+	total := int64(0)
+	for i, bucket := range h.buckets {
+		total += h.counts[i]
+		callback(strconv.FormatFloat(bucket, 'g', -1, 64), total)
+	}
+	callback("+Inf", h.count)
+}
+
+// writePrometheus writes the histogram's bucket, sum, and count series for the route.
+func (h *histogram) writePrometheus(out *strings.Builder, name string, route string) {
+	h.cumulative(func(le string, count int64) {
+		fmt.Fprintf(out, "%s_bucket{route=%s,le=%s} %d\n", name, quoteLabel(route), quoteLabel(le), count)
+	})
+	fmt.Fprintf(out, "%s_sum{route=%s} %s\n", name, quoteLabel(route), strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(out, "%s_count{route=%s} %d\n", name, quoteLabel(route), h.count)
+}
+
+// statusClass converts a status code such as 404 into its class, "4xx".
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// labelEscaper escapes the characters that the Prometheus text format doesn't allow in label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel escapes and quotes a Prometheus label value.
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
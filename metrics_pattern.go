@@ -0,0 +1,13 @@
+//go:build go1.22
+// +build go1.22
+
+package respond
+
+import (
+	"net/http"
+)
+
+// routePattern returns the ServeMux pattern that matched the request (e.g. "GET /users/{id}").
+func routePattern(req *http.Request) string {
+	return req.Pattern
+}
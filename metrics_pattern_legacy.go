@@ -0,0 +1,14 @@
+//go:build !go1.22
+// +build !go1.22
+
+package respond
+
+import (
+	"net/http"
+)
+
+// routePattern returns an empty route since ServeMux doesn't expose the matched pattern prior to
+// Go 1.22. Supply your own MetricsOptions.Route to label requests by route.
+func routePattern(req *http.Request) string {
+	return ""
+}
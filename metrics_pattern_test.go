@@ -0,0 +1,26 @@
+//go:build go1.22
+// +build go1.22
+
+package respond_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/monadicstack/respond"
+)
+
+// By default, we should use the ServeMux pattern rather than the raw path as the route.
+func (suite RespondSuite) TestMetrics_pattern() {
+	metrics := respond.NewMetrics(respond.MetricsOptions{})
+	factory := respond.NewFactory(respond.WithMetrics(metrics))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/123", nil)
+	req.Pattern = "GET /users/{id}"
+	factory.To(newResponseWriter(), req).Ok("hello")
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	suite.Contains(w.Body.String(), `respond_requests_total{route="GET /users/{id}",method="GET",status="2xx"} 1`+"\n")
+	suite.NotContains(w.Body.String(), "/users/123")
+}
@@ -0,0 +1,139 @@
+package respond_test
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+func newTestMetrics() *respond.Metrics {
+	return respond.NewMetrics(respond.MetricsOptions{
+		LatencyBuckets: []float64{0.1, 1},
+		SizeBuckets:    []float64{10, 100},
+		Route: func(req *http.Request) string {
+			return req.Header.Get("X-Route")
+		},
+	})
+}
+
+func (suite RespondSuite) TestMetrics_prometheus() {
+	metrics := newTestMetrics()
+	factory := respond.NewFactory(respond.WithMetrics(metrics))
+
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", "/users/{id}")).Ok(mockUser{ID: 123, Name: "Bob"})
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", "/users/{id}")).NotFound("user not found: %s", strings.Repeat("x", 100))
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodDelete, "/users/123", "X-Route", "/users/{id}")).NoContent()
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", `/a"b`)).HTML("<p>Hi</p>")
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", "")).Ok("hello")
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	suite.Equal(200, w.Code)
+	suite.Equal("text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	suite.Contains(body, "# TYPE respond_requests_total counter\n")
+	suite.Contains(body, `respond_requests_total{route="/users/{id}",method="DELETE",status="2xx"} 1`+"\n")
+	suite.Contains(body, `respond_requests_total{route="/users/{id}",method="GET",status="2xx"} 1`+"\n")
+	suite.Contains(body, `respond_requests_total{route="/users/{id}",method="GET",status="4xx"} 1`+"\n")
+	suite.Contains(body, `respond_requests_total{route="/a\"b",method="GET",status="2xx"} 1`+"\n")
+	suite.Contains(body, `respond_requests_total{route="unknown",method="GET",status="2xx"} 1`+"\n")
+
+	// The 204 and the user JSON fit in the first bucket, but the long error message doesn't fit in any.
+	suite.Contains(body, "# TYPE respond_response_size_bytes histogram\n")
+	suite.Contains(body, `respond_response_size_bytes_bucket{route="/users/{id}",le="10"} 1`+"\n")
+	suite.Contains(body, `respond_response_size_bytes_bucket{route="/users/{id}",le="100"} 2`+"\n")
+	suite.Contains(body, `respond_response_size_bytes_bucket{route="/users/{id}",le="+Inf"} 3`+"\n")
+	suite.Contains(body, `respond_response_size_bytes_count{route="/users/{id}"} 3`+"\n")
+
+	suite.Contains(body, "# TYPE respond_request_duration_seconds histogram\n")
+	suite.Contains(body, `respond_request_duration_seconds_bucket{route="/users/{id}",le="0.1"} 3`+"\n")
+	suite.Contains(body, `respond_request_duration_seconds_count{route="/users/{id}"} 3`+"\n")
+}
+
+// The middleware should make the latency include the entire handler, not just the write.
+func (suite RespondSuite) TestMetrics_middleware() {
+	metrics := newTestMetrics()
+	factory := respond.NewFactory(respond.WithMetrics(metrics))
+	handler := metrics.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		factory.To(w, req).Ok("hello")
+	}))
+
+	handler.ServeHTTP(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", "/slow"))
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	suite.Contains(w.Body.String(), `respond_request_duration_seconds_bucket{route="/slow",le="0.1"} 0`+"\n")
+	suite.Contains(w.Body.String(), `respond_request_duration_seconds_bucket{route="/slow",le="1"} 1`+"\n")
+}
+
+// Metrics should be publishable via expvar, which expects a JSON String().
+func (suite RespondSuite) TestMetrics_expvar() {
+	metrics := newTestMetrics()
+	factory := respond.NewFactory(respond.WithMetrics(metrics))
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", "/users/{id}")).Ok("hello")
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", "/users/{id}")).InternalServerError("nope")
+
+	var snapshot struct {
+		Routes map[string]struct {
+			Requests map[string]map[string]int64 `json:"requests"`
+			Size     struct {
+				Buckets map[string]int64 `json:"buckets"`
+				Sum     float64          `json:"sum"`
+				Count   int64            `json:"count"`
+			} `json:"sizeBytes"`
+		} `json:"routes"`
+	}
+	suite.Require().NoError(json.Unmarshal([]byte(metrics.String()), &snapshot))
+
+	route := snapshot.Routes["/users/{id}"]
+	suite.Equal(map[string]map[string]int64{"GET": {"2xx": 1, "5xx": 1}}, route.Requests)
+	suite.Equal(int64(2), route.Size.Count)
+	suite.Equal(map[string]int64{"10": 1, "100": 2, "+Inf": 2}, route.Size.Buckets)
+}
+
+// A response that fails after its status was sent should only be counted once.
+func (suite RespondSuite) TestMetrics_failAfterWrite() {
+	metrics := newTestMetrics()
+	factory := respond.NewFactory(respond.WithMetrics(metrics))
+	temp := template.Must(template.New("metrics").Parse(`<p>{{ .Missing }}</p>`))
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodGet, "/users/123", "X-Route", "/users/{id}")).HTMLTemplate(temp, "Bob")
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, newHTTPRequest(http.MethodGet, "/metrics"))
+	body := w.Body.String()
+	suite.Contains(body, `respond_requests_total{route="/users/{id}",method="GET",status="2xx"} 1`+"\n")
+	suite.NotContains(body, `status="5xx"`)
+	suite.Contains(body, `respond_request_duration_seconds_count{route="/users/{id}"} 1`+"\n")
+	suite.Contains(body, `respond_response_size_bytes_count{route="/users/{id}"} 1`+"\n")
+}
+
+// Non-standard methods shouldn't each get their own label value.
+func (suite RespondSuite) TestMetrics_unknownMethod() {
+	metrics := newTestMetrics()
+	factory := respond.NewFactory(respond.WithMetrics(metrics))
+	factory.To(newResponseWriter(), newHTTPRequest("BREW", "/coffee", "X-Route", "/coffee")).Ok("hello")
+	factory.To(newResponseWriter(), newHTTPRequest("PROPFIND", "/coffee", "X-Route", "/coffee")).Ok("hello")
+	factory.To(newResponseWriter(), newHTTPRequest(http.MethodPatch, "/coffee", "X-Route", "/coffee")).Ok("hello")
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, newHTTPRequest(http.MethodGet, "/metrics"))
+	body := w.Body.String()
+	suite.Contains(body, `respond_requests_total{route="/coffee",method="OTHER",status="2xx"} 2`+"\n")
+	suite.Contains(body, `respond_requests_total{route="/coffee",method="PATCH",status="2xx"} 1`+"\n")
+	suite.NotContains(body, "BREW")
+}
+
+func (suite RespondSuite) TestMetrics_empty() {
+	w := httptest.NewRecorder()
+	respond.NewMetrics(respond.MetricsOptions{}).Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	suite.Equal(200, w.Code)
+	suite.Contains(w.Body.String(), "# TYPE respond_requests_total counter\n")
+	suite.Equal(`{"routes":{}}`, respond.NewMetrics(respond.MetricsOptions{}).String())
+}
@@ -0,0 +1,181 @@
+package respond
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// sniffLength is the number of bytes that http.DetectContentType() considers when sniffing content.
+const sniffLength = 512
+
+// defaultMIMETypes is the built-in mapping of file extensions to content types. We use our own table
+// rather than the "mime" package since its results vary based on the OS (e.g. /etc/mime.types), and
+// we want the same file to be served with the same Content-Type no matter where you deploy.
+var defaultMIMETypes = map[string]string{
+	// Text/documents
+	".css":         "text/css; charset=utf-8",
+	".csv":         "text/csv; charset=utf-8",
+	".htm":         "text/html; charset=utf-8",
+	".html":        "text/html; charset=utf-8",
+	".ics":         "text/calendar; charset=utf-8",
+	".md":          "text/markdown; charset=utf-8",
+	".txt":         "text/plain; charset=utf-8",
+	".tsv":         "text/tab-separated-values; charset=utf-8",
+	".vtt":         "text/vtt; charset=utf-8",
+	".xml":         "text/xml; charset=utf-8",
+	".pdf":         "application/pdf",
+	".rtf":         "application/rtf",
+	".doc":         "application/msword",
+	".docx":        "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":         "application/vnd.ms-excel",
+	".xlsx":        "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":         "application/vnd.ms-powerpoint",
+	".pptx":        "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":         "application/vnd.oasis.opendocument.text",
+	".ods":         "application/vnd.oasis.opendocument.spreadsheet",
+	".epub":        "application/epub+zip",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".jsonld":      "application/ld+json",
+	".wasm":        "application/wasm",
+	".yaml":        "application/yaml",
+	".yml":         "application/yaml",
+	".webmanifest": "application/manifest+json",
+	// Images
+	".avif": "image/avif",
+	".bmp":  "image/bmp",
+	".gif":  "image/gif",
+	".ico":  "image/vnd.microsoft.icon",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".webp": "image/webp",
+	// Audio/video
+	".aac":  "audio/aac",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mid":  "audio/midi",
+	".midi": "audio/midi",
+	".mp3":  "audio/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/opus",
+	".wav":  "audio/wav",
+	".weba": "audio/webm",
+	".avi":  "video/x-msvideo",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".mp4":  "video/mp4",
+	".mpeg": "video/mpeg",
+	".ogv":  "video/ogg",
+	".webm": "video/webm",
+	// Fonts
+	".eot":   "application/vnd.ms-fontobject",
+	".otf":   "font/otf",
+	".ttf":   "font/ttf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	// Archives/binaries
+	".7z":  "application/x-7z-compressed",
+	".bin": "application/octet-stream",
+	".bz2": "application/x-bzip2",
+	".gz":  "application/gzip",
+	".jar": "application/java-archive",
+	".rar": "application/vnd.rar",
+	".tar": "application/x-tar",
+	".zip": "application/zip",
+}
+
+// WithMIMEType registers the content type that the factory's responders should use when serving
+// files with the given extension (e.g. ".foo" or "foo"). This overrides the built-in type for that
+// extension if there is one. If the content type is a "text/*" type and doesn't specify a charset,
+// we'll assume "utf-8".
+func WithMIMEType(extension string, contentType string) FactoryOption {
+	return func(factory *Factory) {
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		factory.mimeTypes[strings.ToLower(extension)] = withCharset(contentType)
+	}
+}
+
+// contentTypeByExtension looks up the content type for the file name's extension, returning an
+// empty string if the file has no extension or we don't know about that extension.
+func (factory *Factory) contentTypeByExtension(fileName string) string {
+	ext := strings.ToLower(path.Ext(fileName))
+	if ext == "" {
+		return ""
+	}
+	return factory.mimeTypes[ext]
+}
+
+// resolveContentType determines the Content-Type we should use when serving the file. We first
+// look at the file's extension, but if that doesn't give us anything we'll sniff the first few
+// bytes of the data. Since sniffing consumes part of the stream, you should use the returned
+// reader to read the full content rather than the original one.
+func (factory *Factory) resolveContentType(fileName string, data io.Reader) (string, io.Reader, error) {
+	if contentType := factory.contentTypeByExtension(fileName); contentType != "" {
+		return contentType, data, nil
+	}
+	return sniffContentType(data)
+}
+
+// sniffContentType reads up to the first 512 bytes of the data and uses http.DetectContentType()
+// to figure out what type of content it is. Since this consumes part of the stream, you should use
+// the returned reader to read the full content rather than the original one; if the data is seekable
+// we'll just seek back to where we started and give you the original reader. When there's no data
+// at all, this just assumes "application/octet-stream".
+func sniffContentType(data io.Reader) (string, io.Reader, error) {
+	if data == nil {
+		return "application/octet-stream", data, nil
+	}
+
+	seeker, seekable := data.(io.ReadSeeker)
+	offset := int64(0)
+	if seekable {
+		var err error
+		if offset, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(data, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+
+	buf = buf[:n]
+	contentType := "application/octet-stream"
+	if n > 0 {
+		contentType = withCharset(http.DetectContentType(buf))
+	}
+
+	if seekable {
+		if _, err = seeker.Seek(offset, io.SeekStart); err != nil {
+			return "", nil, err
+		}
+		return contentType, data, nil
+	}
+	return contentType, io.MultiReader(bytes.NewReader(buf), data), nil
+}
+
+// withCharset makes sure that all "text/*" content types include an explicit charset, assuming
+// "utf-8" when there isn't one already. All other content types are returned as-is.
+func withCharset(contentType string) string {
+	if !strings.HasPrefix(strings.ToLower(contentType), "text/") {
+		return contentType
+	}
+	if strings.Contains(strings.ToLower(contentType), "charset=") {
+		return contentType
+	}
+	return contentType + "; charset=utf-8"
+}
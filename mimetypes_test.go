@@ -0,0 +1,111 @@
+package respond_test
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/monadicstack/respond"
+)
+
+// The built-in table should be used regardless of what the OS thinks these extensions are.
+func (suite RespondSuite) TestServe_builtInTypes() {
+	types := map[string]string{
+		"app.js":      "text/javascript; charset=utf-8",
+		"style.CSS":   "text/css; charset=utf-8",
+		"data.csv":    "text/csv; charset=utf-8",
+		"data.json":   "application/json",
+		"image.webp":  "image/webp",
+		"font.woff2":  "font/woff2",
+		"report.xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"app.wasm":    "application/wasm",
+	}
+	for fileName, contentType := range types {
+		w := newResponseWriter()
+		respond.To(w, newRequest()).ServeBytes(fileName, []byte("hello"))
+		suite.assertHeader(w, "Content-Type", contentType)
+	}
+}
+
+// Unknown extensions should fall back to sniffing the content.
+func (suite RespondSuite) TestServe_sniffed() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Serve("picture", bytes.NewReader([]byte("\x89PNG\x0D\x0A\x1A\x0A...")))
+	suite.assertHeader(w, "Content-Type", "image/png")
+	suite.assertBody(w, "\x89PNG\x0D\x0A\x1A\x0A...")
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Download("page.goblins", strings.NewReader("<html><body>Hi</body></html>"))
+	suite.assertHeader(w, "Content-Type", "text/html; charset=utf-8")
+	suite.assertBody(w, "<html><body>Hi</body></html>")
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).ServeBytes("nothing", []byte{})
+	suite.assertHeader(w, "Content-Type", "application/octet-stream")
+	suite.assertEmptyBody(w)
+}
+
+// Make sure that we don't lose any data when sniffing content larger than the sniff buffer.
+func (suite RespondSuite) TestServe_sniffedLarge() {
+	content := strings.Repeat("hello world ", 1000)
+
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Serve("big", strings.NewReader(content))
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertBody(w, content)
+}
+
+func (suite RespondSuite) TestServe_customTypes() {
+	responders := respond.NewFactory(
+		respond.WithMIMEType(".goblins", "application/x-goblins"),
+		respond.WithMIMEType("GREMLINS", "text/x-gremlins"),
+		respond.WithMIMEType("csv", "text/csv; charset=iso-8859-1"),
+	)
+
+	w := newResponseWriter()
+	responders.To(w, newRequest()).ServeBytes("foo.goblins", []byte("hello"))
+	suite.assertHeader(w, "Content-Type", "application/x-goblins")
+
+	w = newResponseWriter()
+	responders.To(w, newRequest()).ServeBytes("foo.gremlins", []byte("hello"))
+	suite.assertHeader(w, "Content-Type", "text/x-gremlins; charset=utf-8")
+
+	w = newResponseWriter()
+	responders.To(w, newRequest()).DownloadBytes("foo.csv", []byte("hello"))
+	suite.assertHeader(w, "Content-Type", "text/csv; charset=iso-8859-1")
+
+	// Custom types should not leak into other factories.
+	w = newResponseWriter()
+	respond.To(w, newRequest()).ServeBytes("foo.goblins", []byte("hello"))
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+}
+
+// Raw content w/o a ContentTypeReader should use the file name if it has one, then sniff.
+func (suite RespondSuite) TestRaw_contentTypeResolved() {
+	type R struct {
+		rawContentReader
+		rawContentFileName
+	}
+
+	w := newResponseWriter()
+	result := R{}
+	result.reader = newRawString("a,b,c")
+	result.fileName = "report.csv"
+	respond.To(w, newRequest()).Ok(result)
+	suite.assertHeader(w, "Content-Type", "text/csv; charset=utf-8")
+	suite.assertRaw(w, "a,b,c")
+
+	w = newResponseWriter()
+	result = R{}
+	result.reader = newRawString("%PDF-1.7 ...")
+	respond.To(w, newRequest()).Ok(result)
+	suite.assertHeader(w, "Content-Type", "application/pdf")
+	suite.assertRaw(w, "%PDF-1.7 ...")
+}
+
+// If the reader fails while sniffing, we haven't written anything yet, so we can fail cleanly.
+func (suite RespondSuite) TestServe_sniffFail() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Serve("foo", badReader{failureStatus: 403})
+	suite.assertError(w, 403, "bad monkey")
+	suite.assertHeader(w, "Content-Disposition", "")
+}
@@ -0,0 +1,103 @@
+// Package respondotel annotates OpenTelemetry spans w/ the details of the responses written by
+// respond's responders. It lives in its own module so that 'respond' itself doesn't depend on
+// OpenTelemetry; only services that want the integration pull it in.
+//
+//	responders := respond.NewFactory(
+//	    respond.WithTracer(respondotel.NewTracer()),
+//	)
+package respondotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/monadicstack/respond"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// FailEventName is the name of the span event we add whenever a responder fails.
+const FailEventName = "respond.fail"
+
+// Tracer is a respond.Tracer that annotates the span in the request's context (typically the
+// one started by your otelhttp middleware) w/ the response's status, size, and content type.
+type Tracer struct{}
+
+// NewTracer creates a tracer that annotates the current OpenTelemetry span for each response.
+func NewTracer() Tracer {
+	return Tracer{}
+}
+
+// TraceResponse sets the response attributes on the current span. For 5XX responses, we also mark the
+// span as an error using the original error's message.
+func (Tracer) TraceResponse(ctx context.Context, info respond.TraceInfo) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", info.Status),
+		attribute.Int64("http.response.body.size", info.BytesWritten),
+	)
+	if info.ContentType != "" {
+		span.SetAttributes(attribute.String("http.response.header.content-type", info.ContentType))
+	}
+	if info.StatusSource != nil {
+		span.SetAttributes(attribute.String("respond.error.status_source", fmt.Sprintf("%T", info.StatusSource)))
+	}
+
+	switch {
+	case info.Status >= http.StatusInternalServerError && info.Err != nil:
+		span.SetStatus(codes.Error, info.Err.Error())
+	case info.Status >= http.StatusInternalServerError:
+		span.SetStatus(codes.Error, http.StatusText(info.Status))
+	case info.WriteErr != nil:
+		span.SetStatus(codes.Error, info.WriteErr.Error())
+	}
+}
+
+// TraceFail adds a "respond.fail" event to the current span that describes every error in the
+// original error's chain, not just the flattened message that we sent to the caller.
+func (Tracer) TraceFail(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() || err == nil {
+		return
+	}
+
+	chain := errorChain(err)
+	types := make([]string, len(chain))
+	messages := make([]string, len(chain))
+	for i, chainErr := range chain {
+		types[i] = fmt.Sprintf("%T", chainErr)
+		messages[i] = chainErr.Error()
+	}
+
+	span.AddEvent(FailEventName, trace.WithAttributes(
+		attribute.String("exception.type", types[0]),
+		attribute.String("exception.message", messages[0]),
+		attribute.StringSlice("respond.error.chain.types", types),
+		attribute.StringSlice("respond.error.chain.messages", messages),
+	))
+}
+
+// errorChain flattens the error and everything it wraps (depth-first) into a single slice,
+// starting w/ the error itself.
+func errorChain(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	chain := []error{err}
+	switch wrapper := err.(type) {
+	case interface{ Unwrap() error }:
+		chain = append(chain, errorChain(wrapper.Unwrap())...)
+	case interface{ Unwrap() []error }:
+		for _, wrapped := range wrapper.Unwrap() {
+			chain = append(chain, errorChain(wrapped)...)
+		}
+	}
+	return chain
+}
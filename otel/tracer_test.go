@@ -0,0 +1,114 @@
+package respondotel_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/monadicstack/respond"
+	"github.com/monadicstack/respond/otel"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracerSuite(t *testing.T) {
+	suite.Run(t, new(TracerSuite))
+}
+
+type TracerSuite struct {
+	suite.Suite
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "user not found" }
+func (notFoundError) Status() int   { return 404 }
+
+// respondWithSpan runs the handler w/ a recording span in the request's context and returns the finished span.
+func (suite *TracerSuite) respondWithSpan(handler func(w http.ResponseWriter, req *http.Request)) sdktrace.ReadOnlySpan {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	req := httptest.NewRequest(http.MethodGet, "/users/123", nil).WithContext(ctx)
+	handler(httptest.NewRecorder(), req)
+	span.End()
+
+	suite.Require().Len(recorder.Ended(), 1)
+	return recorder.Ended()[0]
+}
+
+func (suite *TracerSuite) attributes(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, attr := range attrs {
+		values[attr.Key] = attr.Value
+	}
+	return values
+}
+
+func (suite *TracerSuite) TestSuccess() {
+	factory := respond.NewFactory(respond.WithTracer(respondotel.NewTracer()))
+	span := suite.respondWithSpan(func(w http.ResponseWriter, req *http.Request) {
+		factory.To(w, req).Ok("hello")
+	})
+
+	attrs := suite.attributes(span.Attributes())
+	suite.Equal(int64(200), attrs["http.response.status_code"].AsInt64())
+	suite.Equal(int64(7), attrs["http.response.body.size"].AsInt64())
+	suite.Equal("application/json", attrs["http.response.header.content-type"].AsString())
+	suite.Equal(codes.Unset, span.Status().Code)
+	suite.Len(span.Events(), 0)
+}
+
+func (suite *TracerSuite) TestFail() {
+	factory := respond.NewFactory(respond.WithTracer(respondotel.NewTracer()))
+	err := fmt.Errorf("get user: %w", notFoundError{})
+	span := suite.respondWithSpan(func(w http.ResponseWriter, req *http.Request) {
+		factory.To(w, req).Fail(err)
+	})
+
+	attrs := suite.attributes(span.Attributes())
+	suite.Equal(int64(404), attrs["http.response.status_code"].AsInt64())
+	suite.Equal("respondotel_test.notFoundError", attrs["respond.error.status_source"].AsString())
+	suite.Equal(codes.Unset, span.Status().Code)
+
+	suite.Require().Len(span.Events(), 1)
+	event := span.Events()[0]
+	suite.Equal(respondotel.FailEventName, event.Name)
+
+	eventAttrs := suite.attributes(event.Attributes)
+	suite.Equal("*fmt.wrapError", eventAttrs["exception.type"].AsString())
+	suite.Equal("get user: user not found", eventAttrs["exception.message"].AsString())
+	suite.Equal([]string{"*fmt.wrapError", "respondotel_test.notFoundError"}, eventAttrs["respond.error.chain.types"].AsStringSlice())
+	suite.Equal([]string{"get user: user not found", "user not found"}, eventAttrs["respond.error.chain.messages"].AsStringSlice())
+}
+
+// Server errors should mark the span as failed using the original error's message.
+func (suite *TracerSuite) TestServerError() {
+	factory := respond.NewFactory(respond.WithTracer(respondotel.NewTracer()))
+	err := errors.Join(errors.New("db down"), errors.New("cache down"))
+	span := suite.respondWithSpan(func(w http.ResponseWriter, req *http.Request) {
+		factory.To(w, req).Fail(err)
+	})
+
+	suite.Equal(codes.Error, span.Status().Code)
+	suite.Equal("db down\ncache down", span.Status().Description)
+
+	suite.Require().Len(span.Events(), 1)
+	eventAttrs := suite.attributes(span.Events()[0].Attributes)
+	suite.Equal([]string{"*errors.joinError", "*errors.errorString", "*errors.errorString"}, eventAttrs["respond.error.chain.types"].AsStringSlice())
+}
+
+// Requests w/o a recording span shouldn't blow up.
+func (suite *TracerSuite) TestNoSpan() {
+	factory := respond.NewFactory(respond.WithTracer(respondotel.NewTracer()))
+	w := httptest.NewRecorder()
+	factory.To(w, httptest.NewRequest(http.MethodGet, "/", nil)).InternalServerError("boom")
+	suite.Equal(500, w.Code)
+}
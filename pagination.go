@@ -0,0 +1,234 @@
+package respond
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Page is a single page of results from a list endpoint. When you pass one to Ok(), Reply(), etc.
+// we'll respond with the items as a JSON array, and include RFC 8288 "Link" headers w/ the "first",
+// "prev", "next", and "last" pages (whichever make sense) based on the current request's URL.
+//
+// Use PageNumber() for page-number-style pagination (?page=2&size=20) or PageCursor() for
+// cursor-style pagination (?cursor=abc123) rather than building a Page by hand.
+type Page struct {
+	// Items is the slice of values on this page.
+	Items interface{}
+	// Number is the 1-based number of this page when using page-number-style pagination.
+	Number int
+	// Size is the maximum number of items on each page.
+	Size int
+	// Total is the total number of items across all pages, or nil if you don't know.
+	Total *int
+	// NextCursor is the cursor for the page after this one when using cursor-style pagination.
+	// Leave it empty if this is the last page.
+	NextCursor string
+	// PrevCursor is the cursor for the page before this one when using cursor-style pagination.
+	// Leave it empty if this is the first page.
+	PrevCursor string
+	// Envelope wraps the items in a JSON object along w/ a "meta" block describing the page
+	// (e.g. {"items":[...],"meta":{"page":2,"totalItems":95,...}}) rather than responding w/ a bare array.
+	Envelope bool
+	// Params are the names of the query string parameters we use to build the pages' links.
+	Params PageParams
+}
+
+// PageParams are the names of the query string parameters for your pagination. We use these to
+// build the "Link" header URLs by modifying the current request's query string.
+type PageParams struct {
+	// Number is the name of the page number parameter. When this is empty, we use "page".
+	Number string
+	// Size is the name of the page size parameter. When this is empty, we use "size".
+	Size string
+	// Cursor is the name of the cursor parameter. When this is empty, we use "cursor".
+	Cursor string
+}
+
+// PageMeta describes a page of results; it's the "meta" block of a Page when you enable Envelope.
+type PageMeta struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"pageSize,omitempty"`
+	TotalItems *int   `json:"totalItems,omitempty"`
+	TotalPages *int   `json:"totalPages,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// pageEnvelope is the JSON structure of a page when you enable Envelope.
+type pageEnvelope struct {
+	Items interface{} `json:"items"`
+	Meta  PageMeta    `json:"meta"`
+}
+
+// PageNumber creates a page for page-number-style pagination. The number is 1-based, and the total
+// is the number of items across all pages (use -1 if you don't know).
+func PageNumber(items interface{}, number int, size int, total int) Page {
+	if number < 1 {
+		number = 1
+	}
+	page := Page{Items: items, Number: number, Size: size}
+	if total >= 0 {
+		page.Total = &total
+	}
+	return page
+}
+
+// PageCursor creates a page for cursor-style pagination. The next/prev cursors are the values of
+// the cursor parameter for the pages after/before this one; leave them empty if there's no such page.
+func PageCursor(items interface{}, nextCursor string, prevCursor string) Page {
+	return Page{Items: items, NextCursor: nextCursor, PrevCursor: prevCursor}
+}
+
+// Meta returns the information about this page that we include in the envelope.
+func (page Page) Meta() PageMeta {
+	meta := PageMeta{
+		Page:       page.Number,
+		PageSize:   page.Size,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	if page.Total != nil {
+		total := *page.Total
+		meta.TotalItems = &total
+		if page.Number > 0 {
+			totalPages := page.totalPages()
+			meta.TotalPages = &totalPages
+		}
+	}
+	return meta
+}
+
+// writePage writes the "Link"/"X-Total-Count" headers for the page and responds w/ its items.
+func (r Responder) writePage(status int, page Page) {
+	links := page.links(r.request)
+	if len(links) > 0 {
+		header := make([]string, len(links))
+		for i, link := range links {
+			header[i] = "<" + link.uri + `>; rel="` + link.rel + `"`
+		}
+		r.writer.Header().Set("Link", strings.Join(header, ", "))
+	}
+	if page.Total != nil {
+		r.writer.Header().Set("X-Total-Count", strconv.Itoa(*page.Total))
+	}
+	if r.factory.jsonAPI {
+		r.writeJSONAPI(status, page.Items, links, page.Meta())
+		return
+	}
+
+	// Sparse fieldsets apply to the individual items, even when they're wrapped in an envelope.
+	fields, err := r.requestedFields(page.Items)
+	if err != nil {
+		r.Fail(err)
+		return
+	}
+	if !page.Envelope {
+		r.writeSparseJSON(status, page.Items, fields)
+		return
+	}
+	if fields != nil {
+		fields = fields.wrap(fieldTree{"items": fields.tree, "meta": fieldTree{}})
+	}
+	r.writeSparseJSON(status, pageEnvelope{Items: page.Items, Meta: page.Meta()}, fields)
+}
+
+// pageLink is a link to one of the other pages, such as the "next" one.
+type pageLink struct {
+	rel string
+	uri string
+}
+
+// links builds the RFC 8288 links (e.g. `</users?page=3>; rel="next"`) to the other pages.
+func (page Page) links(req *http.Request) []pageLink {
+	if req == nil || req.URL == nil {
+		return nil
+	}
+
+	params := page.Params.withDefaults()
+	var links []pageLink
+	link := func(rel string, modify func(query url.Values)) {
+		query := req.URL.Query()
+		modify(query)
+		uri := url.URL{Path: req.URL.Path, RawPath: req.URL.RawPath, RawQuery: query.Encode()}
+		links = append(links, pageLink{rel: rel, uri: uri.String()})
+	}
+
+	// Cursor-style pagination.
+	if page.Number <= 0 {
+		if page.PrevCursor != "" {
+			link("first", func(query url.Values) { query.Del(params.Cursor) })
+			link("prev", func(query url.Values) { query.Set(params.Cursor, page.PrevCursor) })
+		}
+		if page.NextCursor != "" {
+			link("next", func(query url.Values) { query.Set(params.Cursor, page.NextCursor) })
+		}
+		return links
+	}
+
+	// Page-number-style pagination.
+	setPage := func(number int) func(query url.Values) {
+		return func(query url.Values) {
+			query.Set(params.Number, strconv.Itoa(number))
+			if page.Size > 0 {
+				query.Set(params.Size, strconv.Itoa(page.Size))
+			}
+		}
+	}
+	link("first", setPage(1))
+	if page.Number > 1 {
+		link("prev", setPage(page.Number-1))
+	}
+	if page.hasNext() {
+		link("next", setPage(page.Number+1))
+	}
+	if page.Total != nil && page.Size > 0 {
+		link("last", setPage(page.totalPages()))
+	}
+	return links
+}
+
+// hasNext determines if there's a page after this one. If we don't know the total, we assume
+// that there's another page as long as this one is full.
+func (page Page) hasNext() bool {
+	if page.Total != nil && page.Size > 0 {
+		return page.Number < page.totalPages()
+	}
+	return page.Size > 0 && itemCount(page.Items) >= page.Size
+}
+
+// totalPages calculates the number of pages based on the total number of items. There's always at
+// least one page, even if it's empty.
+func (page Page) totalPages() int {
+	if page.Size <= 0 || page.Total == nil || *page.Total <= 0 {
+		return 1
+	}
+	return (*page.Total + page.Size - 1) / page.Size
+}
+
+// withDefaults fills in the default parameter names for any you didn't supply.
+func (params PageParams) withDefaults() PageParams {
+	if params.Number == "" {
+		params.Number = "page"
+	}
+	if params.Size == "" {
+		params.Size = "size"
+	}
+	if params.Cursor == "" {
+		params.Cursor = "cursor"
+	}
+	return params
+}
+
+// itemCount returns the number of items in the page's slice/array (or 0 if it's not one).
+func itemCount(items interface{}) int {
+	value := reflect.ValueOf(items)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		return value.Len()
+	default:
+		return 0
+	}
+}
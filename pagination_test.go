@@ -0,0 +1,152 @@
+package respond_test
+
+import (
+	"net/http"
+
+	"github.com/monadicstack/respond"
+)
+
+func newPageUsers(count int) []mockUser {
+	users := make([]mockUser, count)
+	for i := range users {
+		users[i] = mockUser{ID: i + 1, Name: "Bob"}
+	}
+	return users
+}
+
+func (suite RespondSuite) TestPage_number() {
+	w := newResponseWriter()
+	users := []mockUser{{ID: 3, Name: "Bob"}}
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users?page=2&size=2&sort=name")).Ok(respond.PageNumber(users, 2, 2, 5))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `[{"id":3,"name":"Bob"}]`)
+	suite.assertHeader(w, "X-Total-Count", "5")
+	suite.assertHeader(w, "Link", `</users?page=1&size=2&sort=name>; rel="first", `+
+		`</users?page=1&size=2&sort=name>; rel="prev", `+
+		`</users?page=3&size=2&sort=name>; rel="next", `+
+		`</users?page=3&size=2&sort=name>; rel="last"`)
+}
+
+func (suite RespondSuite) TestPage_numberFirstAndLast() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(respond.PageNumber(newPageUsers(10), 1, 10, 25))
+	suite.assertHeader(w, "Link", `</users?page=1&size=10>; rel="first", `+
+		`</users?page=2&size=10>; rel="next", `+
+		`</users?page=3&size=10>; rel="last"`)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users?page=3")).Ok(respond.PageNumber(newPageUsers(5), 3, 10, 25))
+	suite.assertHeader(w, "Link", `</users?page=1&size=10>; rel="first", `+
+		`</users?page=2&size=10>; rel="prev", `+
+		`</users?page=3&size=10>; rel="last"`)
+
+	// An empty result set still has one (empty) page.
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(respond.PageNumber([]mockUser{}, 1, 10, 0))
+	suite.assertBody(w, `[]`)
+	suite.assertHeader(w, "X-Total-Count", "0")
+	suite.assertHeader(w, "Link", `</users?page=1&size=10>; rel="first", </users?page=1&size=10>; rel="last"`)
+}
+
+// When we don't know the total, a full page implies that there might be another one.
+func (suite RespondSuite) TestPage_numberUnknownTotal() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users?page=2")).Ok(respond.PageNumber(newPageUsers(3), 2, 3, -1))
+	suite.assertHeader(w, "X-Total-Count", "")
+	suite.assertHeader(w, "Link", `</users?page=1&size=3>; rel="first", `+
+		`</users?page=1&size=3>; rel="prev", `+
+		`</users?page=3&size=3>; rel="next"`)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users?page=2")).Ok(respond.PageNumber(newPageUsers(2), 2, 3, -1))
+	suite.assertHeader(w, "Link", `</users?page=1&size=3>; rel="first", </users?page=1&size=3>; rel="prev"`)
+}
+
+func (suite RespondSuite) TestPage_cursor() {
+	w := newResponseWriter()
+	page := respond.PageCursor(newPageUsers(2), "c3", "c1")
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users?cursor=c2&limit=2")).Ok(page)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Total-Count", "")
+	suite.assertHeader(w, "Link", `</users?limit=2>; rel="first", `+
+		`</users?cursor=c1&limit=2>; rel="prev", `+
+		`</users?cursor=c3&limit=2>; rel="next"`)
+
+	// The first page has nothing before it, and the last has nothing after it.
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(respond.PageCursor(newPageUsers(2), "c3", ""))
+	suite.assertHeader(w, "Link", `</users?cursor=c3>; rel="next"`)
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(respond.PageCursor(newPageUsers(2), "", ""))
+	suite.assertHeader(w, "Link", "")
+}
+
+// A page that you build by hand doesn't know its total unless you tell it.
+func (suite RespondSuite) TestPage_handBuilt() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(respond.Page{Items: newPageUsers(1), NextCursor: "abc", Envelope: true})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Total-Count", "")
+	suite.assertBody(w, `{"items":[{"id":1,"name":"Bob"}],"meta":{"nextCursor":"abc"}}`)
+
+	total := 0
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(respond.Page{Items: []mockUser{}, Total: &total, Envelope: true})
+	suite.assertHeader(w, "X-Total-Count", "0")
+	suite.assertBody(w, `{"items":[],"meta":{"totalItems":0}}`)
+}
+
+func (suite RespondSuite) TestPage_customParams() {
+	w := newResponseWriter()
+	page := respond.PageNumber(newPageUsers(2), 1, 2, 4)
+	page.Params = respond.PageParams{Number: "p", Size: "per_page"}
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users?p=1")).Ok(&page)
+	suite.assertHeader(w, "Link", `</users?p=1&per_page=2>; rel="first", `+
+		`</users?p=2&per_page=2>; rel="next", `+
+		`</users?p=2&per_page=2>; rel="last"`)
+
+	w = newResponseWriter()
+	page = respond.PageCursor(newPageUsers(2), "next page", "")
+	page.Params = respond.PageParams{Cursor: "after"}
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(page)
+	suite.assertHeader(w, "Link", `</users?after=next+page>; rel="next"`)
+}
+
+func (suite RespondSuite) TestPage_envelope() {
+	w := newResponseWriter()
+	page := respond.PageNumber(newPageUsers(1), 2, 1, 3)
+	page.Envelope = true
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(page)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"items":[{"id":1,"name":"Bob"}],"meta":{"page":2,"pageSize":1,"totalItems":3,"totalPages":3}}`)
+
+	w = newResponseWriter()
+	page = respond.PageCursor(newPageUsers(1), "c2", "")
+	page.Envelope = true
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(page)
+	suite.assertBody(w, `{"items":[{"id":1,"name":"Bob"}],"meta":{"nextCursor":"c2"}}`)
+}
+
+// Pages should still respect errors and status codes like any other value.
+func (suite RespondSuite) TestPage_errorAndStatus() {
+	w := newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Ok(respond.PageNumber(newPageUsers(1), 1, 1, 1), errorWithStatus{status: 403, message: "nope"})
+	suite.assertError(w, 403, "nope")
+	suite.assertHeader(w, "Link", "")
+
+	w = newResponseWriter()
+	respond.To(w, newHTTPRequest(http.MethodGet, "/users")).Reply(206, respond.PageNumber(newPageUsers(1), 1, 1, 1))
+	suite.assertStatus(w, 206)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(respond.PageNumber(newPageUsers(1), 1, 1, 1))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Link", "")
+	suite.assertHeader(w, "X-Total-Count", "1")
+
+	w = newResponseWriter()
+	var nilPage *respond.Page
+	respond.To(w, newRequest()).Ok(nilPage)
+	suite.assertBody(w, "null")
+}
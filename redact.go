@@ -0,0 +1,41 @@
+package respond
+
+import (
+	"context"
+)
+
+// RedactedValue is what we write in place of the value of a field tagged w/ respond:"redact" when
+// the caller isn't allowed to see it.
+const RedactedValue = "[REDACTED]"
+
+// viewContextKey is the context key where ContextWithView() stores the request's view.
+type viewContextKey struct{}
+
+// ContextWithView returns a copy of the context where responders will only include the struct
+// fields tagged w/ respond:"view=..." when they list this view (e.g. "admin"). Typically, your
+// authentication middleware would decide the view based on the caller's role.
+//
+//	ctx := respond.ContextWithView(req.Context(), "admin")
+//	next.ServeHTTP(w, req.WithContext(ctx))
+func ContextWithView(ctx context.Context, view string) context.Context {
+	return context.WithValue(ctx, viewContextKey{}, view)
+}
+
+// ViewFromContext returns the view that ContextWithView() attached to the context. It's
+// empty if there isn't one.
+func ViewFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	view, _ := ctx.Value(viewContextKey{}).(string)
+	return view
+}
+
+// View returns the view for the request we're responding to, which determines which of your
+// struct fields tagged w/ respond:"view=..." we include in the response.
+func (r Responder) View() string {
+	if r.request == nil {
+		return ""
+	}
+	return ViewFromContext(r.request.Context())
+}
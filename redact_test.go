@@ -0,0 +1,186 @@
+package respond_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+type redactAccount struct {
+	ID           string `json:"id"`
+	Email        string `json:"email" respond:"redact"`
+	PasswordHash string `json:"passwordHash" respond:"omit"`
+	Notes        string `json:"notes,omitempty" respond:"view=admin|support"`
+	SSN          string `json:"ssn" respond:"redact,view=admin"`
+	Balance      int64  `json:"balance,string"`
+	Nickname     string `json:"nickname,omitempty"`
+	Typo         string `json:"typo" respond:"redcat"`
+}
+
+type redactAudit struct {
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy" respond:"view=admin"`
+}
+
+type redactTeam struct {
+	*redactAudit
+	Name     string                   `json:"name"`
+	Owner    *redactAccount           `json:"owner"`
+	Members  []redactAccount          `json:"members"`
+	ByEmail  map[string]redactAccount `json:"byEmail,omitempty"`
+	Extra    interface{}              `json:"extra,omitempty"`
+	Children []*redactTeam            `json:"children,omitempty"`
+}
+
+func newRedactAccount() redactAccount {
+	return redactAccount{
+		ID:           "a1",
+		Email:        "bob@example.com",
+		PasswordHash: "$2a$10$abc",
+		Notes:        "likes cats",
+		SSN:          "123-45-6789",
+		Balance:      100,
+		Typo:         "oops",
+	}
+}
+
+func newViewRequest(uri string, view string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, uri, nil)
+	if view != "" {
+		req = req.WithContext(respond.ContextWithView(req.Context(), view))
+	}
+	return req
+}
+
+func (suite RespondSuite) TestRedact_noView() {
+	w := newResponseWriter()
+	respond.To(w, newViewRequest("/accounts/a1", "")).Ok(newRedactAccount())
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"id":"a1","email":"[REDACTED]","ssn":"[REDACTED]","balance":"100"}`)
+}
+
+func (suite RespondSuite) TestRedact_views() {
+	w := newResponseWriter()
+	respond.To(w, newViewRequest("/accounts/a1", "admin")).Ok(newRedactAccount())
+	suite.assertBody(w, `{"id":"a1","email":"[REDACTED]","notes":"likes cats","ssn":"123-45-6789","balance":"100"}`)
+
+	w = newResponseWriter()
+	respond.To(w, newViewRequest("/accounts/a1", "support")).Ok(&redactAccount{ID: "a2", Notes: "x"})
+	suite.assertBody(w, `{"id":"a2","email":"[REDACTED]","notes":"x","ssn":"[REDACTED]","balance":"0"}`)
+
+	w = newResponseWriter()
+	respond.To(w, newViewRequest("/accounts/a1", "guest")).Ok(newRedactAccount())
+	suite.assertBody(w, `{"id":"a1","email":"[REDACTED]","ssn":"[REDACTED]","balance":"100"}`)
+}
+
+// Restricted fields should be handled no matter where they're nested.
+func (suite RespondSuite) TestRedact_nested() {
+	owner := newRedactAccount()
+	team := redactTeam{
+		redactAudit: &redactAudit{CreatedAt: time.Date(2020, 5, 12, 0, 0, 0, 0, time.UTC), CreatedBy: "alice"},
+		Name:        "Cats",
+		Owner:       &owner,
+		Members:     []redactAccount{{ID: "a2"}},
+		ByEmail:     map[string]redactAccount{"x@example.com": {ID: "a3"}},
+		Extra:       map[string]interface{}{"account": redactAccount{ID: "a4"}},
+		Children:    []*redactTeam{{Name: "Kittens"}},
+	}
+
+	w := newResponseWriter()
+	respond.To(w, newViewRequest("/teams/t1", "")).Ok(team)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"createdAt":"2020-05-12T00:00:00Z","name":"Cats",`+
+		`"owner":{"id":"a1","email":"[REDACTED]","ssn":"[REDACTED]","balance":"100"},`+
+		`"members":[{"id":"a2","email":"[REDACTED]","ssn":"[REDACTED]","balance":"0"}],`+
+		`"byEmail":{"x@example.com":{"id":"a3","email":"[REDACTED]","ssn":"[REDACTED]","balance":"0"}},`+
+		`"extra":{"account":{"id":"a4","email":"[REDACTED]","ssn":"[REDACTED]","balance":"0"}},`+
+		`"children":[{"name":"Kittens","owner":null,"members":null}]}`)
+
+	w = newResponseWriter()
+	respond.To(w, newViewRequest("/teams/t1", "admin")).Ok(redactTeam{
+		redactAudit: &redactAudit{CreatedBy: "alice"},
+		Name:        "Cats",
+	})
+	suite.assertBody(w, `{"createdAt":"0001-01-01T00:00:00Z","createdBy":"alice","name":"Cats","owner":null,"members":null}`)
+}
+
+// Types w/o any "respond" tags should marshal exactly like they always have.
+func (suite RespondSuite) TestRedact_unrestricted() {
+	w := newResponseWriter()
+	respond.To(w, newViewRequest("/users/u1", "")).Ok(newFieldsUser())
+	suite.assertBody(w, `{"id":"u1","createdAt":"2020-05-12T08:30:00Z","name":"Bob","email":"bob@example.com",`+
+		`"address":{"city":"Boston","country":"US"},"tags":["a","b"],"Notes":"a \"quoted\" {note}, w/ [brackets]"}`)
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).Ok(map[string]interface{}{"a": 1, "b": []interface{}{"c", nil}})
+	suite.assertBody(w, `{"a":1,"b":["c",null]}`)
+}
+
+// Restricted fields should be just as invisible to sparse fieldsets and pages.
+func (suite RespondSuite) TestRedact_sparseFields() {
+	factory := respond.NewFactory(respond.WithSparseFields(""))
+
+	w := newResponseWriter()
+	factory.To(w, newViewRequest("/accounts/a1?fields=id,email", "")).Ok(newRedactAccount())
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `{"id":"a1","email":"[REDACTED]"}`)
+
+	w = newResponseWriter()
+	factory.To(w, newViewRequest("/accounts/a1?fields=passwordHash", "admin")).Ok(newRedactAccount())
+	suite.assertError(w, 400, "unknown field: passwordHash")
+
+	w = newResponseWriter()
+	factory.To(w, newViewRequest("/accounts/a1?fields=id,notes", "")).Ok(newRedactAccount())
+	suite.assertError(w, 400, "unknown field: notes")
+
+	w = newResponseWriter()
+	factory.To(w, newViewRequest("/accounts?fields=id,notes", "admin")).Ok(respond.PageNumber([]redactAccount{newRedactAccount()}, 1, 10, 1))
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `[{"id":"a1","notes":"likes cats"}]`)
+}
+
+func (suite RespondSuite) TestRedact_view() {
+	suite.Equal("", respond.To(newResponseWriter(), newRequest()).View())
+	suite.Equal("", respond.To(newResponseWriter(), nil).View())
+	suite.Equal("admin", respond.To(newResponseWriter(), newViewRequest("/", "admin")).View())
+}
+
+type redactScalars struct {
+	Text   string             `json:"text"`
+	Float  float64            `json:"float"`
+	Small  float32            `json:"small"`
+	Big    float64            `json:"big"`
+	Number json.Number        `json:"number"`
+	ByID   map[int]string     `json:"byId"`
+	Time   time.Time          `json:"time"`
+	Bytes  []byte             `json:"bytes"`
+	Any    interface{}        `json:"any"`
+	Nested map[string]float64 `json:"nested"`
+	Secret string             `json:"secret,omitempty" respond:"view=admin"`
+}
+
+// Values that we walk to apply tags should marshal exactly like encoding/json would.
+func (suite RespondSuite) TestRedact_encoding() {
+	value := redactScalars{
+		Text:   "<a href=\"x\">&</a>\n\t   caf\xe9 \x01 \u2028",
+		Float:  3.25,
+		Small:  0.0000001,
+		Big:    1e21,
+		Number: "12.50",
+		ByID:   map[int]string{10: "b", 2: "a"},
+		Time:   time.Date(2020, 5, 12, 8, 30, 0, 0, time.UTC),
+		Bytes:  []byte("hi"),
+		Any:    []interface{}{1.5, "x", nil, true},
+		Nested: map[string]float64{"b": -0.000001, "a": 100},
+	}
+	expected, err := json.Marshal(value)
+	suite.Require().NoError(err)
+
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Ok(value)
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, string(expected))
+}
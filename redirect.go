@@ -0,0 +1,256 @@
+package respond
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RedirectPolicy protects your handlers from "open redirect" attacks where an attacker crafts
+// a link to your site that redirects the user to some malicious site (e.g. "/login?next=https://evil.example").
+// The policy is applied to every redirect a responder performs, so it doesn't matter whether the
+// URL came from Redirect(), RedirectTo(), or a Redirector returned to Reply().
+//
+// Relative URLs like "/foo/bar" are always allowed. Protocol-relative URLs like "//evil.example"
+// are rejected unless you explicitly allow them. Absolute URLs must use an allowed scheme and,
+// depending on your settings, point at an allowed host.
+type RedirectPolicy struct {
+	// AllowedHosts are the hosts that absolute redirect URLs are allowed to point at. You can use a
+	// leading wildcard to allow all subdomains (e.g. "*.example.com" allows "api.example.com" but
+	// not "example.com"). The host of the incoming request is always allowed. When this is empty,
+	// absolute URLs may point at any host.
+	AllowedHosts []string
+	// AllowedSchemes are the URL schemes that absolute redirect URLs are allowed to use. When this
+	// is empty, only "http" and "https" are allowed.
+	AllowedSchemes []string
+	// SameOrigin only allows relative URLs and absolute URLs that point at the same scheme and host
+	// as the incoming request. When enabled, AllowedHosts is ignored.
+	SameOrigin bool
+	// TrustForwardedProto uses the "X-Forwarded-Proto" header to determine the scheme of the incoming
+	// request for SameOrigin. Only enable this when you're behind a proxy that sets (or strips) the header.
+	TrustForwardedProto bool
+	// AllowProtocolRelative allows URLs like "//example.com/foo" that inherit the scheme of the
+	// current page. These are rejected by default since they're a common open redirect trick. When
+	// allowed, the host is still subject to the AllowedHosts/SameOrigin rules.
+	AllowProtocolRelative bool
+	// Fallback is the URL we redirect to instead when the requested URL violates the policy. When
+	// this is empty, violations fail with a 400 Bad Request instead.
+	Fallback string
+}
+
+// allows determines whether the policy lets us redirect to the given URL for this request.
+func (policy RedirectPolicy) allows(req *http.Request, uri string) bool {
+	// Browsers treat backslashes like forward slashes, so "/\evil.example" is really "//evil.example".
+	if strings.HasPrefix(uri, `\`) || strings.HasPrefix(uri, `/\`) {
+		return false
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case u.Scheme == "" && u.Host == "":
+		return true
+	case u.Scheme == "" && !policy.AllowProtocolRelative:
+		return false
+	case u.Scheme != "" && !policy.allowsScheme(u.Scheme):
+		return false
+	}
+	return policy.allowsHost(req, u)
+}
+
+// allowsScheme determines whether absolute redirect URLs can use the given scheme.
+func (policy RedirectPolicy) allowsScheme(scheme string) bool {
+	if len(policy.AllowedSchemes) == 0 {
+		return strings.EqualFold(scheme, "http") || strings.EqualFold(scheme, "https")
+	}
+	for _, allowed := range policy.AllowedSchemes {
+		if strings.EqualFold(scheme, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHost determines whether absolute redirect URLs can point at the URL's host. Same-origin URLs
+// must use the request's scheme, too, so an "https" page can't send the user to the "http" version.
+func (policy RedirectPolicy) allowsHost(req *http.Request, u *url.URL) bool {
+	host := u.Hostname()
+	if host == "" {
+		return false
+	}
+	sameHost := req != nil && strings.EqualFold(host, hostName(req.Host))
+	if policy.SameOrigin {
+		return sameHost && (u.Scheme == "" || strings.EqualFold(u.Scheme, requestScheme(req, policy.TrustForwardedProto)))
+	}
+	if sameHost {
+		return true
+	}
+	if len(policy.AllowedHosts) == 0 {
+		return true
+	}
+	for _, allowed := range policy.AllowedHosts {
+		if matchHost(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost compares the host name against an allowed host pattern such as "example.com"
+// or "*.example.com", ignoring case.
+func matchHost(pattern string, host string) bool {
+	pattern = strings.ToLower(pattern)
+	host = strings.ToLower(host)
+
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// hostName strips the port (if any) from a "host:port" value like the one in http.Request.Host.
+func hostName(hostPort string) string {
+	return (&url.URL{Host: hostPort}).Hostname()
+}
+
+// URL is a Redirector that builds its location from a path template rather than printf-style
+// formatting, so you don't have to worry about escaping or mangling "%" sequences. Placeholders
+// in the template are replaced with properly path-escaped values:
+//
+//	"{name}"    - Replaced with the value of Params["name"].
+//	"{name...}" - Replaced with the value of Params["name"], escaping each "/" separated segment
+//	              individually so that the slashes are preserved.
+//	"{}"        - Replaced with the next value from Args.
+//
+// Since it's a Redirector, you can use it with any of the "XxxTo()" redirect functions:
+//
+//	response.RedirectSeeOtherTo(respond.URL{
+//	    Template: "/users/{user}/files/{file}",
+//	    Params:   respond.PathParams{"user": userID, "file": "Résumé 2024.pdf"},
+//	    Query:    url.Values{"tab": []string{"history"}},
+//	})
+type URL struct {
+	// Template is the path (or full URL) containing "{name}" and "{}" placeholders.
+	Template string
+	// Params are the values for the named "{name}" placeholders in the template.
+	Params PathParams
+	// Args are the values for the positional "{}" placeholders in the template, in order.
+	Args []interface{}
+	// Query contains optional query string parameters to append to the URL.
+	Query url.Values
+}
+
+// PathParams contains the values for the named placeholders in a URL template. Values that are
+// not strings will be formatted using fmt.Sprint().
+type PathParams map[string]interface{}
+
+// Path creates a URL whose "{}" placeholders are filled in by the given values, in order.
+func Path(template string, args ...interface{}) URL {
+	return URL{Template: template, Args: args}
+}
+
+// Redirect returns the fully built URL, satisfying the Redirector interface. This returns
+// an empty string if the template can't be built; use Build() if you want to know why.
+func (u URL) Redirect() string {
+	uri, _ := u.Build()
+	return uri
+}
+
+// String returns the fully built URL or an empty string if it can't be built.
+func (u URL) String() string {
+	return u.Redirect()
+}
+
+// Build substitutes all of the template's placeholders and appends the query string, returning
+// the final URL. This fails if the template is malformed, it references a named parameter that
+// doesn't exist in Params, or the number of "{}" placeholders doesn't match the number of Args.
+func (u URL) Build() (string, error) {
+	builder := strings.Builder{}
+	template := u.Template
+	argIndex := 0
+
+	for {
+		open := strings.IndexByte(template, '{')
+		if open < 0 {
+			builder.WriteString(template)
+			break
+		}
+		closing := strings.IndexByte(template[open:], '}')
+		if closing < 0 {
+			return "", fmt.Errorf("invalid url template: unclosed placeholder in %q", u.Template)
+		}
+		closing += open
+
+		builder.WriteString(template[:open])
+		name := template[open+1 : closing]
+		template = template[closing+1:]
+
+		if name == "" {
+			if argIndex >= len(u.Args) {
+				return "", fmt.Errorf("invalid url template: not enough args for %q", u.Template)
+			}
+			builder.WriteString(url.PathEscape(fmt.Sprint(u.Args[argIndex])))
+			argIndex++
+			continue
+		}
+
+		multiSegment := strings.HasSuffix(name, "...")
+		name = strings.TrimSuffix(name, "...")
+		value, ok := u.Params[name]
+		if !ok {
+			return "", fmt.Errorf("invalid url template: missing param %q", name)
+		}
+		if multiSegment {
+			builder.WriteString(escapeSegments(fmt.Sprint(value)))
+		} else {
+			builder.WriteString(url.PathEscape(fmt.Sprint(value)))
+		}
+	}
+
+	if argIndex != len(u.Args) {
+		return "", fmt.Errorf("invalid url template: too many args for %q", u.Template)
+	}
+	if len(u.Query) == 0 {
+		return builder.String(), nil
+	}
+
+	// The query goes before the "#fragment" (if any). Substituted values are escaped, so any "#"
+	// or "?" in the built URL came from the template itself.
+	uri, fragment := builder.String(), ""
+	if hash := strings.IndexByte(uri, '#'); hash >= 0 {
+		uri, fragment = uri[:hash], uri[hash:]
+	}
+	if strings.Contains(uri, "?") {
+		return uri + "&" + u.Query.Encode() + fragment, nil
+	}
+	return uri + "?" + u.Query.Encode() + fragment, nil
+}
+
+// escapeSegments path-escapes each "/" separated segment of the value individually.
+func escapeSegments(value string) string {
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// resolveRedirect determines the URL we should redirect to for the given redirector. Redirectors
+// that are URL builders get the chance to report why they couldn't build their URL.
+func resolveRedirect(redirector Redirector) (string, error) {
+	switch v := redirector.(type) {
+	case URL:
+		return v.Build()
+	case *URL:
+		if v == nil {
+			return "", nil
+		}
+		return v.Build()
+	default:
+		return redirector.Redirect(), nil
+	}
+}
@@ -0,0 +1,275 @@
+package respond_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/monadicstack/respond"
+)
+
+func (suite RespondSuite) TestRedirectPolicy_none() {
+	w := newResponseWriter()
+	req := newPolicyRequest()
+
+	// Without a policy, we'll happily redirect anywhere (the historical behavior).
+	respond.To(w, req).Redirect("//evil.example/foo")
+	suite.assertStatus(w, 307)
+	suite.assertHeader(w, "Location", "//evil.example/foo")
+}
+
+func (suite RespondSuite) TestRedirectPolicy_relative() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{
+		SameOrigin: true,
+	}))
+
+	suite.assertRedirectAllowed(responders, "/foo/bar?q=1")
+	suite.assertRedirectAllowed(responders, "foo/bar")
+	suite.assertRedirectAllowed(responders, "?page=2")
+}
+
+func (suite RespondSuite) TestRedirectPolicy_protocolRelative() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{}))
+	suite.assertRedirectRejected(responders, "//evil.example/foo")
+	suite.assertRedirectRejected(responders, `/\evil.example/foo`)
+	suite.assertRedirectRejected(responders, `\\evil.example/foo`)
+
+	responders = respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{
+		AllowProtocolRelative: true,
+		AllowedHosts:          []string{"cdn.example.com"},
+	}))
+	suite.assertRedirectAllowed(responders, "//cdn.example.com/foo")
+	suite.assertRedirectRejected(responders, "//evil.example/foo")
+}
+
+func (suite RespondSuite) TestRedirectPolicy_schemes() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{}))
+	suite.assertRedirectAllowed(responders, "https://google.com")
+	suite.assertRedirectAllowed(responders, "HTTP://google.com")
+	suite.assertRedirectRejected(responders, "javascript:alert(1)")
+	suite.assertRedirectRejected(responders, "ftp://google.com")
+
+	responders = respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{
+		AllowedSchemes: []string{"https", "myapp"},
+	}))
+	suite.assertRedirectAllowed(responders, "https://google.com")
+	suite.assertRedirectAllowed(responders, "myapp://settings")
+	suite.assertRedirectRejected(responders, "http://google.com")
+}
+
+func (suite RespondSuite) TestRedirectPolicy_allowedHosts() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{
+		AllowedHosts: []string{"google.com", "*.example.com"},
+	}))
+	suite.assertRedirectAllowed(responders, "https://google.com/foo")
+	suite.assertRedirectAllowed(responders, "https://GOOGLE.com:8443/foo")
+	suite.assertRedirectAllowed(responders, "https://www.example.com/foo")
+	suite.assertRedirectAllowed(responders, "https://api.example.com/foo")
+	suite.assertRedirectRejected(responders, "https://example.com/foo")
+	suite.assertRedirectRejected(responders, "https://evil.example/foo")
+	suite.assertRedirectRejected(responders, "https://google.com.evil.example/foo")
+	suite.assertRedirectRejected(responders, "https://evilexample.com/foo")
+}
+
+func (suite RespondSuite) TestRedirectPolicy_sameOrigin() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{
+		SameOrigin:   true,
+		AllowedHosts: []string{"google.com"},
+	}))
+	suite.assertRedirectAllowed(responders, "http://api.example.com/foo")
+	suite.assertRedirectRejected(responders, "https://google.com/foo")
+	suite.assertRedirectRejected(responders, "https://www.example.com/foo")
+}
+
+// Same-origin redirects can't switch between "http" and "https".
+func (suite RespondSuite) TestRedirectPolicy_sameOriginScheme() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{SameOrigin: true}))
+	suite.assertRedirectRejected(responders, "https://api.example.com/foo")
+
+	w := newResponseWriter()
+	req := newHTTPRequest(http.MethodGet, "https://api.example.com/login")
+	responders.To(w, req).Redirect("http://api.example.com/foo")
+	suite.assertStatus(w, 400)
+
+	w = newResponseWriter()
+	responders.To(w, req).Redirect("https://api.example.com/foo")
+	suite.assertStatus(w, 307)
+
+	// The forwarded scheme is only used when the policy trusts it.
+	forwarded := newHTTPRequest(http.MethodGet, "http://api.example.com/login", "X-Forwarded-Proto", "https")
+	w = newResponseWriter()
+	responders.To(w, forwarded).Redirect("https://api.example.com/foo")
+	suite.assertStatus(w, 400)
+
+	responders = respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{SameOrigin: true, TrustForwardedProto: true}))
+	w = newResponseWriter()
+	responders.To(w, forwarded).Redirect("https://api.example.com/foo")
+	suite.assertStatus(w, 307)
+}
+
+func (suite RespondSuite) TestRedirectPolicy_fallback() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{
+		SameOrigin: true,
+		Fallback:   "/home",
+	}))
+
+	w := newResponseWriter()
+	responders.To(w, newPolicyRequest()).RedirectSeeOther("https://evil.example/foo")
+	suite.assertStatus(w, 303)
+	suite.assertHeader(w, "Location", "/home")
+}
+
+// The policy should apply no matter which flavor of redirect you use.
+func (suite RespondSuite) TestRedirectPolicy_allRedirects() {
+	responders := respond.NewFactory(respond.WithRedirectPolicy(respond.RedirectPolicy{
+		SameOrigin: true,
+	}))
+	evil := fakeRedirector{URL: "https://evil.example"}
+
+	redirects := []func(respond.Responder){
+		func(r respond.Responder) { r.Redirect(evil.URL) },
+		func(r respond.Responder) { r.RedirectTo(evil) },
+		func(r respond.Responder) { r.RedirectPermanent(evil.URL) },
+		func(r respond.Responder) { r.RedirectPermanentTo(evil) },
+		func(r respond.Responder) { r.RedirectSeeOther(evil.URL) },
+		func(r respond.Responder) { r.RedirectFound(evil.URL) },
+		func(r respond.Responder) { r.RedirectMovedPermanently(evil.URL) },
+		func(r respond.Responder) { r.Ok(evil) },
+		func(r respond.Responder) { r.Reply(200, fakeStatusRedirector{URL: evil.URL, Status: 303}) },
+		func(r respond.Responder) { r.JobStatus(respond.Job{State: respond.JobSucceeded, ResultURL: evil.URL}) },
+	}
+	for _, redirect := range redirects {
+		w := newResponseWriter()
+		redirect(responders.To(w, newPolicyRequest()))
+		suite.assertError(w, 400, "redirect url not allowed")
+		suite.assertHeader(w, "Location", "")
+	}
+}
+
+func (suite RespondSuite) assertRedirectAllowed(responders *respond.Factory, uri string) {
+	w := newResponseWriter()
+	responders.To(w, newPolicyRequest()).Redirect(uri)
+	suite.Require().Equal(307, w.StatusCode, "redirect should be allowed: %s", uri)
+}
+
+func (suite RespondSuite) assertRedirectRejected(responders *respond.Factory, uri string) {
+	w := newResponseWriter()
+	responders.To(w, newPolicyRequest()).Redirect(uri)
+	suite.Require().Equal(400, w.StatusCode, "redirect should be rejected: %s", uri)
+	suite.assertHeader(w, "Location", "")
+}
+
+func newPolicyRequest() *http.Request {
+	return httptest.NewRequest(http.MethodGet, "http://api.example.com:8080/login", nil)
+}
+
+func (suite RespondSuite) TestURL_named() {
+	uri, err := respond.URL{
+		Template: "/users/{user}/files/{file}",
+		Params:   respond.PathParams{"user": 42, "file": "Résumé 2024/final?.pdf"},
+	}.Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/users/42/files/R%C3%A9sum%C3%A9%202024%2Ffinal%3F.pdf", uri)
+}
+
+func (suite RespondSuite) TestURL_multiSegment() {
+	uri, err := respond.URL{
+		Template: "https://{bucket}.s3.amazonaws.com/{key...}",
+		Params:   respond.PathParams{"bucket": "stuff", "key": "a b/c%d/e.txt"},
+	}.Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("https://stuff.s3.amazonaws.com/a%20b/c%25d/e.txt", uri)
+}
+
+func (suite RespondSuite) TestURL_positional() {
+	uri, err := respond.Path("/users/{}/files/{}", "a/b", 100).Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/users/a%2Fb/files/100", uri)
+}
+
+func (suite RespondSuite) TestURL_query() {
+	uri, err := respond.URL{
+		Template: "/search/{}",
+		Args:     []interface{}{"a b"},
+		Query:    url.Values{"q": []string{"50% off & more"}, "page": []string{"2"}},
+	}.Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/search/a%20b?page=2&q=50%25+off+%26+more", uri)
+
+	uri, err = respond.URL{
+		Template: "/search?sort=name",
+		Query:    url.Values{"page": []string{"2"}},
+	}.Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/search?sort=name&page=2", uri)
+
+	uri, err = respond.URL{
+		Template: "/docs/{}#install",
+		Args:     []interface{}{"getting started"},
+		Query:    url.Values{"lang": []string{"go"}},
+	}.Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/docs/getting%20started?lang=go#install", uri)
+
+	uri, err = respond.URL{
+		Template: "/docs?v=2#what?",
+		Query:    url.Values{"lang": []string{"go"}},
+	}.Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/docs?v=2&lang=go#what?", uri)
+}
+
+// Percent sequences in the template itself are not touched the way printf would mangle them.
+func (suite RespondSuite) TestURL_literalPercent() {
+	uri, err := respond.Path("/files/hello%20world/{}", "x").Build()
+	suite.Require().NoError(err)
+	suite.Require().Equal("/files/hello%20world/x", uri)
+}
+
+func (suite RespondSuite) TestURL_invalid() {
+	_, err := respond.Path("/users/{}/files/{}", "a").Build()
+	suite.Require().Error(err)
+
+	_, err = respond.Path("/users/{}", "a", "b").Build()
+	suite.Require().Error(err)
+
+	_, err = respond.Path("/users/{user").Build()
+	suite.Require().Error(err)
+
+	_, err = respond.URL{Template: "/users/{user}"}.Build()
+	suite.Require().Error(err)
+	suite.Require().Equal("", respond.URL{Template: "/users/{user}"}.Redirect())
+}
+
+func (suite RespondSuite) TestURL_redirect() {
+	w := newResponseWriter()
+	respond.To(w, newPolicyRequest()).RedirectSeeOtherTo(respond.Path("/users/{}", "Bob Loblaw"))
+	suite.assertStatus(w, 303)
+	suite.assertHeader(w, "Location", "/users/Bob%20Loblaw")
+
+	w = newResponseWriter()
+	respond.To(w, newPolicyRequest()).Ok(respond.Path("/users/{}", "Bob Loblaw"))
+	suite.assertStatus(w, 307)
+	suite.assertHeader(w, "Location", "/users/Bob%20Loblaw")
+}
+
+// URLs that can't be built should fail with the reason rather than a generic "empty url".
+func (suite RespondSuite) TestURL_redirectInvalid() {
+	w := newResponseWriter()
+	respond.To(w, newPolicyRequest()).RedirectTo(respond.URL{Template: "/users/{user}"})
+	suite.assertError(w, 500, `invalid url template: missing param \"user\"`)
+}
+
+// Printf-style redirects always go through fmt.Sprintf, even w/o args, so escaped "%%" sequences
+// keep working. Pre-built URLs should use RedirectTo() instead.
+func (suite RespondSuite) TestRedirect_literalPercent() {
+	w := newResponseWriter()
+	respond.To(w, newPolicyRequest()).Redirect("https://google.com/a%%20b%%2Fc")
+	suite.assertStatus(w, 307)
+	suite.assertHeader(w, "Location", "https://google.com/a%20b%2Fc")
+
+	w = newResponseWriter()
+	respond.To(w, newPolicyRequest()).RedirectTo(respond.Path("https://google.com/a%20b%2Fc"))
+	suite.assertStatus(w, 307)
+	suite.assertHeader(w, "Location", "https://google.com/a%20b%2Fc")
+}
@@ -0,0 +1,132 @@
+package respond
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// maxRequestIDLength is the longest incoming request ID we'll accept before generating our own.
+const maxRequestIDLength = 128
+
+// RequestIDOptions customizes how we determine the ID of each request.
+type RequestIDOptions struct {
+	// Header is the request header we read the caller's ID from as well as the response header
+	// we echo it back in. When this is empty, we use "X-Request-ID".
+	Header string
+	// Generator creates a new ID when the caller didn't supply a (valid) one. When this is nil,
+	// we generate 16 random bytes, hex encoded.
+	Generator func() string
+}
+
+// requestIDContextKey is the context key where the RequestID() middleware stores the ID.
+type requestIDContextKey struct{}
+
+// WithRequestID makes the factory's responders determine an ID for every request. We'll use the
+// incoming request header if the caller sent one, otherwise we'll generate a new ID. Either way,
+// the ID is echoed in the response header and included in the body of any error we respond with.
+//
+// If you're using the RequestID() middleware, you don't need this; all responders (even the
+// ones from the package-level To() function) pick up the ID that the middleware determined.
+func WithRequestID(options RequestIDOptions) FactoryOption {
+	options = options.withDefaults()
+	return func(factory *Factory) {
+		factory.requestID = &options
+	}
+}
+
+// RequestID creates middleware that determines an ID for every request, using the incoming request
+// header if the caller sent one, otherwise generating a new ID. The ID is echoed in the response
+// header and stored in the request's context, so responders include it in error bodies and you can
+// grab it for your logs using RequestIDFromContext().
+//
+//	handler = respond.RequestID(respond.RequestIDOptions{})(handler)
+func RequestID(options RequestIDOptions) func(http.Handler) http.Handler {
+	options = options.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := options.resolve(req)
+			w.Header().Set(options.Header, id)
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestIDContextKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID that the RequestID() middleware stored in the
+// context. It's empty if the request didn't pass through the middleware.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// RequestID returns the ID of the request this responder is replying to. This is empty unless
+// you used the RequestID() middleware or created the responder from a factory using WithRequestID().
+func (r Responder) RequestID() string {
+	return r.requestID
+}
+
+// withDefaults fills in the default header/generator for any options you didn't supply.
+func (options RequestIDOptions) withDefaults() RequestIDOptions {
+	if options.Header == "" {
+		options.Header = "X-Request-ID"
+	}
+	if options.Generator == nil {
+		options.Generator = newRequestID
+	}
+	return options
+}
+
+// resolve uses the caller's request ID if it's valid, otherwise it generates a new one.
+func (options RequestIDOptions) resolve(req *http.Request) string {
+	if req != nil {
+		if id := req.Header.Get(options.Header); isValidRequestID(id) {
+			return id
+		}
+	}
+	return options.Generator()
+}
+
+// resolveRequestID determines the ID for the request, preferring the one from the RequestID()
+// middleware, and echoes it in the response if the factory was the one to determine it.
+func (factory *Factory) resolveRequestID(w http.ResponseWriter, req *http.Request) string {
+	if req != nil {
+		if id := RequestIDFromContext(req.Context()); id != "" {
+			return id
+		}
+	}
+	if factory.requestID == nil {
+		return ""
+	}
+
+	id := factory.requestID.resolve(req)
+	w.Header().Set(factory.requestID.Header, id)
+	return id
+}
+
+// isValidRequestID determines if the caller-supplied ID is safe to echo back and write to logs. We only
+// accept reasonably short IDs made up of letters, digits, and a handful of separators.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random, hex encoded request ID.
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
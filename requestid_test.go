@@ -0,0 +1,131 @@
+package respond_test
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/monadicstack/respond"
+)
+
+// Without the middleware or factory option, responses shouldn't have any notion of a request ID.
+func (suite RespondSuite) TestRequestID_default() {
+	w := newResponseWriter()
+	response := respond.To(w, newHTTPRequest(http.MethodGet, "/foo", "X-Request-ID", "abc-123"))
+	response.NotFound("nope")
+	suite.assertError(w, 404, "nope")
+	suite.assertHeader(w, "X-Request-ID", "")
+	suite.Equal("", response.RequestID())
+	suite.NotContains(string(w.Body), "requestId")
+}
+
+func (suite RespondSuite) TestRequestID_middleware() {
+	var contextID string
+	handler := respond.RequestID(respond.RequestIDOptions{})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		contextID = respond.RequestIDFromContext(req.Context())
+		respond.To(w, req).NotFound("nope")
+	}))
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo", "X-Request-ID", "abc-123"))
+	suite.assertError(w, 404, "nope")
+	suite.assertHeader(w, "X-Request-ID", "abc-123")
+	suite.assertJSON(w, "requestId", "abc-123")
+	suite.Equal("abc-123", contextID)
+
+	// No incoming ID, so we should generate one.
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo"))
+	suite.assertStatus(w, 404)
+	suite.Len(w.Header().Get("X-Request-ID"), 32)
+	suite.Equal(w.Header().Get("X-Request-ID"), contextID)
+	suite.assertJSON(w, "requestId", contextID)
+}
+
+// Successful responses should echo the ID in the header, but we shouldn't muck w/ the body.
+func (suite RespondSuite) TestRequestID_success() {
+	handler := respond.RequestID(respond.RequestIDOptions{})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		respond.To(w, req).Ok(mockUser{ID: 123, Name: "Bob"})
+	}))
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo", "X-Request-ID", "abc-123"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Request-ID", "abc-123")
+	suite.NotContains(string(w.Body), "abc-123")
+}
+
+// We shouldn't echo back garbage (or log injection attempts) that the caller sent as their ID.
+func (suite RespondSuite) TestRequestID_invalid() {
+	options := respond.RequestIDOptions{Generator: func() string { return "generated" }}
+	handler := respond.RequestID(options)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		respond.To(w, req).NoContent()
+	}))
+
+	invalidIDs := []string{
+		"abc 123",
+		"abc\r\nX-Foo: bar",
+		"<script>",
+		strings.Repeat("a", 129),
+	}
+	for _, id := range invalidIDs {
+		w := newResponseWriter()
+		req := newHTTPRequest(http.MethodGet, "/foo")
+		req.Header["X-Request-Id"] = []string{id}
+		handler.ServeHTTP(w, req)
+		suite.assertHeader(w, "X-Request-ID", "generated")
+	}
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo", "X-Request-ID", strings.Repeat("a", 128)))
+	suite.assertHeader(w, "X-Request-ID", strings.Repeat("a", 128))
+}
+
+func (suite RespondSuite) TestRequestID_customHeader() {
+	options := respond.RequestIDOptions{Header: "X-Correlation-ID", Generator: func() string { return "generated" }}
+	handler := respond.RequestID(options)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		respond.To(w, req).BadRequest("bad")
+	}))
+
+	w := newResponseWriter()
+	req := newHTTPRequest(http.MethodGet, "/foo", "X-Request-ID", "ignored")
+	req.Header.Set("X-Correlation-ID", "corr-1")
+	handler.ServeHTTP(w, req)
+	suite.assertError(w, 400, "bad")
+	suite.assertHeader(w, "X-Correlation-ID", "corr-1")
+	suite.assertHeader(w, "X-Request-ID", "")
+	suite.assertJSON(w, "requestId", "corr-1")
+
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo", "X-Request-ID", "ignored"))
+	suite.assertHeader(w, "X-Correlation-ID", "generated")
+}
+
+// Factories can determine request IDs themselves when you're not using the middleware.
+func (suite RespondSuite) TestRequestID_factory() {
+	factory := respond.NewFactory(respond.WithRequestID(respond.RequestIDOptions{
+		Generator: func() string { return "generated" },
+	}))
+
+	w := newResponseWriter()
+	response := factory.To(w, newHTTPRequest(http.MethodGet, "/foo", "X-Request-ID", "abc-123"))
+	suite.Equal("abc-123", response.RequestID())
+	response.Fail(errorWithStatus{status: 409, message: "conflict"})
+	suite.assertError(w, 409, "conflict")
+	suite.assertHeader(w, "X-Request-ID", "abc-123")
+	suite.assertJSON(w, "requestId", "abc-123")
+
+	w = newResponseWriter()
+	factory.To(w, newRequest()).Ok("hello")
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Request-ID", "generated")
+
+	// The middleware's ID should win so that both agree on a single ID.
+	handler := respond.RequestID(respond.RequestIDOptions{})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		factory.To(w, req).NotFound("nope")
+	}))
+	w = newResponseWriter()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/foo"))
+	suite.assertStatus(w, 404)
+	suite.assertJSON(w, "requestId", w.Header().Get("X-Request-ID"))
+	suite.NotEqual("generated", w.Header().Get("X-Request-ID"))
+}
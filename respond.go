@@ -2,19 +2,18 @@ package respond
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"html/template"
 	"io"
-	"mime"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 )
 
 // To creates a "Responder" that replies to the inputs for the given HTTP request. For style/consistency
 // purposes, this should be the first line of your HTTP handler: `response := responder.To(w, req)`
 func To(w http.ResponseWriter, req *http.Request) Responder {
-	return Responder{writer: w, request: req}
+	return defaultFactory.To(w, req)
 }
 
 // Redirector defines a type that your handler can "return" to one of the responder functions to indicate that this
@@ -25,6 +24,15 @@ type Redirector interface {
 	Redirect() string
 }
 
+// RedirectStatusReader lets a Redirector that you pass to Reply(), Ok(), etc. decide which 3XX status
+// code we should use for the redirect. By default, redirecting values result in a 307 TEMPORARY
+// redirect, but you can implement this to have them respond with a 301, 302, 303, or 308 instead.
+type RedirectStatusReader interface {
+	// RedirectStatus returns the 3XX HTTP status code to use when redirecting. Any value other
+	// than 301, 302, 303, 307, or 308 will be ignored and we'll use a 307 instead.
+	RedirectStatus() int
+}
+
 // ContentReader indicates that the value you're responding with is actually raw byte content and
 // not something that should be JSON-marshaled. The data read from the resulting io.Reader is what
 // we will send back to the caller.
@@ -36,9 +44,10 @@ type ContentReader interface {
 // ContentTypeReader provides details about a file-based response to indicate what we should
 // use as the "Content-Type" header. Any io.Reader that 'respond' comes across will be
 // treated as raw bytes, not a JSON-marshaled payload. By default, the Content-Type of the response
-// will be "application/octet-stream", but if your result implements this interface, you can tell the
-// responder what type to use instead. For instance, if the result is a JPG, you can have your result
-// return "image/jpeg" and 'respond' will use that in the header instead of octet-stream.
+// is based on the ContentFileName() extension (if you have one) or by sniffing the first 512 bytes
+// of the content, but if your result implements this interface, you can tell the responder what type
+// to use instead. For instance, if the result is a JPG, you can have your result return "image/jpeg"
+// and 'respond' will use that in the header instead of guessing.
 type ContentTypeReader interface {
 	// ContentType returns the "Content-Type" header you want to apply to the HTTP response. This
 	// only applies when the result is a ContentReader, so you're returning raw results.
@@ -62,11 +71,36 @@ type ContentFileNameReader interface {
 	ContentFileName() string
 }
 
+// ContentLengthReader provides the size of a file-based response so that we can include a
+// "Content-Length" header. Without it, clients can't show download progress and proxies fall
+// back to chunked encoding. You don't need to implement this if your content is an *os.File,
+// fs.File, *bytes.Reader, or anything else with a Stat() or Len() function; we'll figure it out.
+type ContentLengthReader interface {
+	// ContentLength returns the number of bytes in the raw content or -1 if you don't know. This
+	// only applies when the result is a ContentReader, so you're returning raw results.
+	ContentLength() int64
+}
+
+// ContentModTimeReader provides the modification time of a file-based response so that we can
+// include a "Last-Modified" header and respond to conditional requests (e.g. "If-Modified-Since")
+// with a 304. You don't need to implement this if your content is an *os.File or fs.File; we'll
+// use the modification time from its Stat() info.
+type ContentModTimeReader interface {
+	// ContentModTime returns the time the content was last modified or the zero time if you don't
+	// know. This only applies when the result is a ContentReader, so you're returning raw results.
+	ContentModTime() time.Time
+}
+
 // Responder provides helper functions for marshaling Go values/streams to send back to the user as well as
 // applying the correct status code and headers. It's the core data structure for this package.
 type Responder struct {
-	writer  http.ResponseWriter
-	request *http.Request
+	writer    http.ResponseWriter
+	request   *http.Request
+	factory   *Factory
+	nonce     string
+	requestID string
+	failure   error
+	state     *responseState
 }
 
 // Reply lets you respond with the custom status code of your choice and a JSON-marshaled version of your value.
@@ -80,16 +114,48 @@ func (r Responder) Reply(status int, value interface{}, errs ...error) {
 	switch v := value.(type) {
 	case Redirector:
 		// The value you're returning is telling us redirect to another URL instead.
-		r.Redirect(v.Redirect())
+		r.redirectTo(redirectStatus(v), v)
 	case ContentReader:
 		// The value looks like a file or some other raw, non-JSON content
-		writeRaw(r.writer, status, v)
+		r.writeRaw(status, v)
+	case Page:
+		// It's one page of a list, so include links to the other pages.
+		r.writePage(status, v)
+	case *Page:
+		if v == nil {
+			r.writeJSON(status, nil)
+			return
+		}
+		r.writePage(status, *v)
+	case *HAL:
+		if v == nil {
+			r.writeJSON(status, nil)
+			return
+		}
+		r.writeHAL(status, *v)
+	case HALResource:
+		// It's a hypermedia resource w/ links to related resources.
+		r.writeHAL(status, v)
 	default:
 		// It's just some returned value that we should marshal as JSON and send back.
-		writeJSON(r.writer, status, value)
+		r.replyJSON(status, value)
 	}
 }
 
+// replyJSON writes the value as JSON, trimming it down to the sparse fieldset if the caller asked for one.
+func (r Responder) replyJSON(status int, value interface{}) {
+	if r.factory.jsonAPI {
+		r.writeJSONAPI(status, value, nil, nil)
+		return
+	}
+	fields, err := r.requestedFields(value)
+	if err != nil {
+		r.Fail(err)
+		return
+	}
+	r.writeSparseJSON(status, value, fields)
+}
+
 // Ok writes a 200 style response to the caller by marshalling the given raw value. If
 // you provided an error, we'll ignore the value and return the appropriate 4XX/5XX
 // response instead.
@@ -119,9 +185,17 @@ func (r Responder) HTML(markup string, errs ...error) {
 		return
 	}
 
+	r.writeSecurityHeaders()
 	r.writer.Header().Set("Content-Type", "text/html; charset=utf-8")
-	r.writer.WriteHeader(http.StatusOK)
-	_, _ = r.writer.Write([]byte(markup))
+	r.writer.Header().Set("Content-Length", strconv.Itoa(len(markup)))
+	_ = r.write(http.StatusOK, markup, func(w http.ResponseWriter) error {
+		w.WriteHeader(http.StatusOK)
+		if r.isHead() {
+			return nil
+		}
+		_, err := w.Write([]byte(markup))
+		return err
+	})
 }
 
 // HTMLTemplate accepts your pre-parsed html template and evaluates it using the given context value. All of
@@ -133,14 +207,17 @@ func (r Responder) HTMLTemplate(htmlTemplate *template.Template, ctxValue interf
 		return
 	}
 
+	r.writeSecurityHeaders()
 	r.writer.Header().Set("Content-Type", "text/html; charset=utf-8")
-	r.writer.WriteHeader(http.StatusOK)
-
-	if htmlTemplate == nil {
-		return
-	}
-
-	err := htmlTemplate.Execute(r.writer, ctxValue)
+	err := r.write(http.StatusOK, ctxValue, func(w http.ResponseWriter) error {
+		w.WriteHeader(http.StatusOK)
+		if htmlTemplate == nil || r.isHead() {
+			return nil
+		}
+		r.exposeCSPNonce(ctxValue)
+		defer r.serverTimings().Start("render")()
+		return htmlTemplate.Execute(w, ctxValue)
+	})
 	if err != nil {
 		r.Fail(err)
 	}
@@ -154,7 +231,7 @@ func (r Responder) NoContent(errs ...error) {
 		r.Fail(err)
 		return
 	}
-	r.writer.WriteHeader(http.StatusNoContent)
+	r.writeStatus(http.StatusNoContent)
 }
 
 // Serve responds with some sort of file data in an inline fashion. This lets you deliver
@@ -162,26 +239,13 @@ func (r Responder) NoContent(errs ...error) {
 // to embed directly in the client. The file name in this case case is simply used to determine
 // the proper Content-Type to include in the response.
 //
+// If the data is an *os.File, fs.File, *bytes.Reader, etc. we'll include the "Content-Length" and
+// "Last-Modified" headers, and if it's seekable we'll support conditional and Range requests, too.
+//
 // It will read your 'data' stream to completion but it will still be up to you to Close() it
 // afterwards if need be.
 func (r Responder) Serve(fileName string, data io.Reader, errs ...error) {
-	if err := firstError(errs...); err != nil {
-		r.Fail(err)
-		return
-	}
-
-	r.writer.Header().Set("Content-Type", fileNameToContentType(fileName))
-	r.writer.Header().Set("Content-Disposition", "inline")
-	r.writer.WriteHeader(http.StatusOK)
-
-	if data == nil {
-		return
-	}
-
-	_, err := io.Copy(r.writer, data)
-	if err != nil {
-		r.Fail(err)
-	}
+	r.serveFile(fileName, "inline", data, errs...)
 }
 
 // ServeBytes responds with some sort of file data in an inline fashion. This lets you deliver
@@ -189,7 +253,7 @@ func (r Responder) Serve(fileName string, data io.Reader, errs ...error) {
 // to embed directly in the client. The file name in this case case is simply used to determine
 // the proper Content-Type to include in the response.
 func (r Responder) ServeBytes(fileName string, data []byte, errs ...error) {
-	r.Serve(fileName, bytes.NewBuffer(data), errs...)
+	r.Serve(fileName, bytes.NewReader(data), errs...)
 }
 
 // Download delivers the file data to the client/caller in a way that indicates that it should
@@ -197,73 +261,138 @@ func (r Responder) ServeBytes(fileName string, data []byte, errs ...error) {
 // determines the Content-Type header we'll use in the response as well as be the default download
 // name that the caller will be presented with in their client/browser.
 //
+// If the data is an *os.File, fs.File, *bytes.Reader, etc. we'll include the "Content-Length" and
+// "Last-Modified" headers, and if it's seekable we'll support conditional and Range requests, too.
+//
 // It will read your 'data' stream to completion but it will still be up to you to Close() it
 // afterwards if need be.
 func (r Responder) Download(fileName string, data io.Reader, errs ...error) {
+	r.serveFile(fileName, attachmentDisposition(fileName), data, errs...)
+}
+
+// DownloadBytes delivers the file data to the client/caller in a way that indicates that it should
+// be given a download prompt (if using a browser or some other UI-based client). The file name
+// determines the Content-Type header we'll use in the response as well as be the default download
+// name that the caller will be presented with in their client/browser.
+func (r Responder) DownloadBytes(fileName string, data []byte, errs ...error) {
+	r.Download(fileName, bytes.NewReader(data), errs...)
+}
+
+// serveFile is the shared logic for Serve() and Download(). It resolves the Content-Type for
+// the file and writes its data using the given Content-Disposition.
+func (r Responder) serveFile(fileName string, disposition string, data io.Reader, errs ...error) {
 	if err := firstError(errs...); err != nil {
 		r.Fail(err)
 		return
 	}
 
-	r.writer.Header().Set("Content-Type", fileNameToContentType(fileName))
-	r.writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
-	r.writer.WriteHeader(http.StatusOK)
+	r.serveContent(fileName, disposition, data, statContent(data))
+}
 
-	if data == nil {
+// serveContent writes the raw data w/ the given size/modification time, using the file name to figure
+// out the Content-Type (sniffing the data if the name doesn't help).
+func (r Responder) serveContent(fileName string, disposition string, data io.Reader, info contentInfo) {
+	contentType, data, err := r.factory.resolveContentType(fileName, data)
+	if err != nil {
+		r.Fail(err)
 		return
 	}
 
-	_, err := io.Copy(r.writer, data)
-	if err != nil {
+	r.writer.Header().Set("Content-Type", contentType)
+	r.writer.Header().Set("Content-Disposition", disposition)
+	r.writer.Header().Set("X-Content-Type-Options", "nosniff")
+	if err = r.writeContent(http.StatusOK, data, info, data); err != nil {
 		r.Fail(err)
 	}
 }
 
-// DownloadBytes delivers the file data to the client/caller in a way that indicates that it should
-// be given a download prompt (if using a browser or some other UI-based client). The file name
-// determines the Content-Type header we'll use in the response as well as be the default download
-// name that the caller will be presented with in their client/browser.
-func (r Responder) DownloadBytes(fileName string, data []byte, errs ...error) {
-	r.Download(fileName, bytes.NewBuffer(data), errs...)
-}
-
 // Redirect performs a 307-style TEMPORARY redirect to the given resource. You can use printf-style
-// formatting to make it easier to build the location you're redirecting to.
+// formatting to make it easier to build the location you're redirecting to, so literal "%" characters
+// must be escaped as "%%". If you already have a fully built URL, or you're interpolating values that
+// need escaping, use RedirectTo() with a URL instead.
 func (r Responder) Redirect(uriFormat string, args ...interface{}) {
-	uri := fmt.Sprintf(uriFormat, args...)
-	if uri == "" {
-		r.Fail(fmt.Errorf("unable to redirect to empty url"))
-		return
-	}
-	http.Redirect(r.writer, r.request, uri, http.StatusTemporaryRedirect)
+	r.redirect(http.StatusTemporaryRedirect, fmt.Sprintf(uriFormat, args...))
 }
 
 // RedirectTo performs a 307-style TEMPORARY redirect to the URL returned by calling Redirect() on your value.
 func (r Responder) RedirectTo(redirector Redirector, errs ...error) {
-	if err := firstError(errs...); err != nil {
-		r.Fail(err)
-		return
-	}
-	if redirector == nil {
-		r.Fail(fmt.Errorf("unable to redirect using nil redirector"))
-		return
-	}
-	r.Redirect(redirector.Redirect())
+	r.redirectTo(http.StatusTemporaryRedirect, redirector, errs...)
 }
 
 // RedirectPermanent performs a 308-style PERMANENT redirect to the given resource. You can use printf-style
 // formatting to make it easier to build the location you're redirecting to.
 func (r Responder) RedirectPermanent(uriFormat string, args ...interface{}) {
-	uri := fmt.Sprintf(uriFormat, args...)
+	r.redirect(http.StatusPermanentRedirect, fmt.Sprintf(uriFormat, args...))
+}
+
+// RedirectPermanentTo performs a 308-style PERMANENT redirect to the URL returned by calling Redirect() on your value.
+func (r Responder) RedirectPermanentTo(redirector Redirector, errs ...error) {
+	r.redirectTo(http.StatusPermanentRedirect, redirector, errs...)
+}
+
+// RedirectSeeOther performs a 303-style SEE OTHER redirect to the given resource. This is the one you
+// typically want after handling a form POST since the client will follow up with a GET rather than
+// re-submitting the form. You can use printf-style formatting to make it easier to build the location
+// you're redirecting to.
+func (r Responder) RedirectSeeOther(uriFormat string, args ...interface{}) {
+	r.redirect(http.StatusSeeOther, fmt.Sprintf(uriFormat, args...))
+}
+
+// RedirectSeeOtherTo performs a 303-style SEE OTHER redirect to the URL returned by calling Redirect() on your value.
+func (r Responder) RedirectSeeOtherTo(redirector Redirector, errs ...error) {
+	r.redirectTo(http.StatusSeeOther, redirector, errs...)
+}
+
+// RedirectFound performs a 302-style FOUND redirect to the given resource. Prefer Redirect() for new
+// code; this exists mainly to support legacy clients/links that expect a 302. You can use printf-style
+// formatting to make it easier to build the location you're redirecting to.
+func (r Responder) RedirectFound(uriFormat string, args ...interface{}) {
+	r.redirect(http.StatusFound, fmt.Sprintf(uriFormat, args...))
+}
+
+// RedirectFoundTo performs a 302-style FOUND redirect to the URL returned by calling Redirect() on your value.
+func (r Responder) RedirectFoundTo(redirector Redirector, errs ...error) {
+	r.redirectTo(http.StatusFound, redirector, errs...)
+}
+
+// RedirectMovedPermanently performs a 301-style MOVED PERMANENTLY redirect to the given resource. Prefer
+// RedirectPermanent() for new code; this exists mainly to support legacy link migrations that expect
+// a 301. You can use printf-style formatting to make it easier to build the location you're redirecting to.
+func (r Responder) RedirectMovedPermanently(uriFormat string, args ...interface{}) {
+	r.redirect(http.StatusMovedPermanently, fmt.Sprintf(uriFormat, args...))
+}
+
+// RedirectMovedPermanentlyTo performs a 301-style MOVED PERMANENTLY redirect to the URL returned by
+// calling Redirect() on your value.
+func (r Responder) RedirectMovedPermanentlyTo(redirector Redirector, errs ...error) {
+	r.redirectTo(http.StatusMovedPermanently, redirector, errs...)
+}
+
+// redirect writes a 3XX response w/ the given status and "Location" header. Redirecting to
+// an empty URL results in a 500 error instead. If the factory has a RedirectPolicy and the URL
+// violates it, we either redirect to the policy's fallback or fail with a 400.
+func (r Responder) redirect(status int, uri string) {
 	if uri == "" {
 		r.Fail(fmt.Errorf("unable to redirect to empty url"))
 		return
 	}
-	http.Redirect(r.writer, r.request, uri, http.StatusPermanentRedirect)
+
+	if policy := r.factory.redirectPolicy; policy != nil && !policy.allows(r.request, uri) {
+		if policy.Fallback == "" {
+			r.BadRequest("redirect url not allowed")
+			return
+		}
+		uri = policy.Fallback
+	}
+	_ = r.write(status, uri, func(w http.ResponseWriter) error {
+		http.Redirect(w, r.request, uri, status)
+		return nil
+	})
 }
 
-// RedirectPermanentTo performs a 308-style PERMANENT redirect to the URL returned by calling Redirect() on your value.
-func (r Responder) RedirectPermanentTo(redirector Redirector, errs ...error) {
+// redirectTo is the shared logic for all of the "XxxTo()" redirect variants. It fails if you supplied
+// an error or a nil redirector, otherwise it redirects to the URL the redirector gives us.
+func (r Responder) redirectTo(status int, redirector Redirector, errs ...error) {
 	if err := firstError(errs...); err != nil {
 		r.Fail(err)
 		return
@@ -272,7 +401,12 @@ func (r Responder) RedirectPermanentTo(redirector Redirector, errs ...error) {
 		r.Fail(fmt.Errorf("unable to redirect using nil redirector"))
 		return
 	}
-	r.RedirectPermanent(redirector.Redirect())
+	uri, err := resolveRedirect(redirector)
+	if err != nil {
+		r.Fail(err)
+		return
+	}
+	r.redirect(status, uri)
 }
 
 // NotModified writes a 304 response with no content. You typically will use this when performing
@@ -282,16 +416,29 @@ func (r Responder) NotModified(errs ...error) {
 		r.Fail(err)
 		return
 	}
-	r.writer.WriteHeader(http.StatusNotModified)
+	r.writeStatus(http.StatusNotModified)
 }
 
 // Fail accepts the error generated by your handler and responds with the most appropriate
 // 4XX/5XX status code and message for that error. It tries to unwrap the error looking for
 // an error with either a Status(), StatusCode(), or Code() function (see the ErrorXXX
 // interfaces in this package) to determine what HTTP status code we will try to fail with.
+//
+// If we've already sent the status/headers for this response (e.g. a template that failed halfway
+// through rendering), it's too late to send an error response, so we only run the on-fail hooks.
 func (r Responder) Fail(err error) {
+	r.fail(err)
+	if r.state.written {
+		return
+	}
+	r.failure = err
 	errResponse := toErrorResponse(err)
-	writeJSON(r.writer, errResponse.Status, errResponse)
+	errResponse.RequestID = r.requestID
+	if r.factory.jsonAPI {
+		r.writeBody(errResponse.Status, JSONAPIContentType, errResponse, newJSONAPIErrors(errResponse), nil)
+		return
+	}
+	r.writeJSON(errResponse.Status, errResponse)
 }
 
 // BadRequest responds w/ a 400 status and a body that contains the status/message.
@@ -372,56 +519,130 @@ func (r Responder) GatewayTimeout(msg string, args ...interface{}) {
 	r.Fail(errorResponse{Status: http.StatusGatewayTimeout, Message: msg})
 }
 
-// writeJSON marshals the result 'value' as JSON and writes the bytes to the response.
-func writeJSON(res http.ResponseWriter, status int, value interface{}) {
-	jsonBytes, err := json.Marshal(value)
+// writeJSON marshals the result 'value' as JSON and writes the bytes to the response. For HEAD
+// requests, we still marshal the value so that the headers match what a GET would give you, but
+// we don't bother writing the body.
+func (r Responder) writeJSON(status int, value interface{}) {
+	r.writeSparseJSON(status, value, nil)
+}
+
+// writeSparseJSON marshals the result 'value' as JSON, leaving out any restricted fields that the
+// caller isn't allowed to see and pruning any fields that aren't in the sparse fieldset, then writes
+// the bytes to the response. A nil fieldset keeps every field. If the factory has an envelope, we
+// wrap the value in it first.
+func (r Responder) writeSparseJSON(status int, value interface{}, fields *sparseFields) {
+	if r.factory.envelope != nil {
+		body, envelopeFields := r.envelope(status, value, fields)
+		r.writeBody(status, "application/json", value, body, envelopeFields)
+		return
+	}
+	r.writeBody(status, "application/json", value, value, fields)
+}
+
+// writeBody marshals the body as JSON and writes it to the response w/ the given content type.
+// The body is usually the value you responded with, but it may be a document that wraps it
+// (e.g. HAL or JSON:API); hooks still receive your original value.
+func (r Responder) writeBody(status int, contentType string, value interface{}, body interface{}, fields *sparseFields) {
+	stopTiming := r.serverTimings().Start("marshal")
+	jsonBytes, compact, err := r.marshal(body)
+	if err == nil && fields != nil {
+		if !compact {
+			jsonBytes, err = compactJSON(jsonBytes)
+		}
+		if err == nil {
+			jsonBytes = fields.prune(make([]byte, 0, len(jsonBytes)), jsonBytes)
+		}
+	}
+	stopTiming()
 	if err != nil {
-		http.Error(res, "json marshal error: "+err.Error(), http.StatusInternalServerError)
+		r.writeMarshalError(err)
+		return
+	}
+	if err = fields.check(); err != nil {
+		r.Fail(err)
 		return
 	}
 
-	res.Header().Set("Content-Type", "application/json")
-	res.WriteHeader(status)
-	_, _ = res.Write(jsonBytes)
+	r.writer.Header().Set("Content-Type", contentType)
+	r.writer.Header().Set("Content-Length", strconv.Itoa(len(jsonBytes)))
+	_ = r.write(status, value, func(w http.ResponseWriter) error {
+		w.WriteHeader(status)
+		if r.isHead() {
+			return nil
+		}
+		_, err := w.Write(jsonBytes)
+		return err
+	})
+}
+
+// writeMarshalError responds w/ a plain text 500 error when we're unable to marshal the JSON
+// response; there's no point trying to marshal a JSON error at that point.
+func (r Responder) writeMarshalError(err error) {
+	_ = r.write(http.StatusInternalServerError, err, func(w http.ResponseWriter) error {
+		http.Error(w, "json marshal error: "+err.Error(), http.StatusInternalServerError)
+		return err
+	})
+}
+
+// writeStatus writes a response that consists of only the status code and headers; no body.
+func (r Responder) writeStatus(status int) {
+	_ = r.write(status, nil, func(w http.ResponseWriter) error {
+		w.WriteHeader(status)
+		return nil
+	})
+}
+
+// isHead determines if we're responding to a HEAD request, meaning that we should write all
+// of the same headers that a GET would, but not the body.
+func (r Responder) isHead() bool {
+	return r.request != nil && r.request.Method == http.MethodHead
 }
 
 // writeRaw accepts a reader containing the bytes of some file or raw set of data that the
 // user wants to write to the caller.
-func writeRaw(res http.ResponseWriter, status int, value ContentReader) {
+func (r Responder) writeRaw(status int, value ContentReader) {
 	reader := value.Content()
 	if reader == nil {
-		res.WriteHeader(status)
+		r.writeStatus(status)
 		return
 	}
 
 	defer func() { _ = reader.Close() }()
-	res.Header().Set("Content-Type", rawContentType(value))
-	res.Header().Set("Content-Disposition", rawContentDisposition(value))
-	res.WriteHeader(status)
-	_, _ = io.Copy(res, reader)
+	info := statContent(value, reader)
+	contentType, data, err := r.rawContentType(value, reader)
+	if err != nil {
+		r.Fail(err)
+		return
+	}
+
+	r.writer.Header().Set("Content-Type", contentType)
+	r.writer.Header().Set("Content-Disposition", rawContentDisposition(value))
+	_ = r.writeContent(status, value, info, data)
 }
 
-// rawContentType assumes "application/octet-stream" unless the return value implements
-// the ContentTypeReader interface. In that case, this will return the content type
-// that the reader specifies. The result is a valid value for the HTTP "Content-Type" header.
-func rawContentType(value ContentReader) string {
-	contentTyped, ok := value.(ContentTypeReader)
-	if !ok {
-		return "application/octet-stream"
+// rawContentType uses the content type specified by the value if it implements the
+// ContentTypeReader interface. Otherwise, we'll try to figure it out based on the file
+// name's extension (if it implements ContentFileNameReader) or by sniffing the first few
+// bytes of the content. Since sniffing consumes part of the stream, you should use the
+// returned reader to read the full content rather than the original one.
+func (r Responder) rawContentType(value ContentReader, reader io.Reader) (string, io.Reader, error) {
+	if contentTyped, ok := value.(ContentTypeReader); ok {
+		if contentType := contentTyped.ContentType(); contentType != "" {
+			return contentType, reader, nil
+		}
 	}
 
-	contentType := contentTyped.ContentType()
-	if contentType == "" {
-		return "application/octet-stream"
+	fileName := ""
+	if named, ok := value.(ContentFileNameReader); ok {
+		fileName = named.ContentFileName()
 	}
-
-	return contentType
+	return r.factory.resolveContentType(fileName, reader)
 }
 
 // rawContentDisposition returns an appropriate value for the "Content-Disposition"
 // HTTP header. In most cases, this will return "inline", but if the reader implements
 // the ContentFileNameReader interface, this will return "attachment; filename=" with the
-// reader's name specified.
+// reader's name specified (see attachmentDisposition for details).
 func rawContentDisposition(value ContentReader) string {
 	named, ok := value.(ContentFileNameReader)
 	if !ok {
@@ -432,9 +653,28 @@ func rawContentDisposition(value ContentReader) string {
 	if fileName == "" {
 		return "inline"
 	}
+	return attachmentDisposition(fileName)
+}
 
-	fileName = strings.ReplaceAll(fileName, `"`, `\"`)
-	return `attachment; filename="` + fileName + `"`
+// redirectStatus returns the 3XX status code that a Redirector wants to redirect with. This is a 307
+// unless the value implements RedirectStatusReader and gives us a valid redirect status instead. Other
+// 3XX statuses like 304 NOT MODIFIED or 300 MULTIPLE CHOICES aren't redirects to a Location, so we ignore them.
+func redirectStatus(redirector Redirector) int {
+	statusReader, ok := redirector.(RedirectStatusReader)
+	if !ok {
+		return http.StatusTemporaryRedirect
+	}
+
+	switch status := statusReader.RedirectStatus(); status {
+	case http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusSeeOther,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+		return status
+	default:
+		return http.StatusTemporaryRedirect
+	}
 }
 
 // firstError grabs the first non-nil error in the given list of errors. This will return
@@ -447,20 +687,3 @@ func firstError(errs ...error) error {
 	}
 	return nil
 }
-
-// fileNameToContentType to take a file name/path and analyzes the file extension. With that extension, this
-// will return the most relevant mime encoding type string. For example "foo/bar/baz.jpg" will return "image/jpeg".
-// This returns "application/octet-stream" for any file name that doesn't have an extension or for any
-// extension that the OS doesn't have a mime mapping for.
-func fileNameToContentType(fileName string) string {
-	extPeriod := strings.LastIndex(fileName, ".")
-	if extPeriod < 0 {
-		return "application/octet-stream"
-	}
-
-	mimeType := mime.TypeByExtension(fileName[extPeriod:])
-	if mimeType == "" {
-		return "application/octet-stream"
-	}
-	return mimeType
-}
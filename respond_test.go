@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/monadicstack/respond"
@@ -257,7 +258,8 @@ func (suite RespondSuite) TestHTMLTemplate_nilTemplate() {
 	suite.assertEmptyBody(w)
 }
 
-// When the template fails to evaluate, you should get a 500 error.
+// When the template fails to evaluate, we've already sent the 200 status, so we shouldn't tack
+// a JSON error onto the end of the partially rendered page.
 func (suite RespondSuite) TestHTMLTemplate_evalError() {
 	w := newResponseWriter()
 	req := newRequest()
@@ -265,7 +267,9 @@ func (suite RespondSuite) TestHTMLTemplate_evalError() {
 	temp := template.Must(template.New("HTMLTemplate").Parse(`<p>{{ .Foo }} is {{ . }}</p>`))
 	respond.To(w, req).HTMLTemplate(temp, "Bob")
 
-	suite.assertStatus(w, 500)
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/html; charset=utf-8")
+	suite.assertBody(w, "<p>")
 }
 
 func (suite RespondSuite) TestRedirect_empty() {
@@ -426,6 +430,21 @@ func (suite RespondSuite) TestRedirectPermanentTo_errorNilInput() {
 	suite.assertError(w, 403, "nope")
 }
 
+func (suite RespondSuite) TestRedirectSeeOther() {
+	suite.runRedirectTests(303, func(r respond.Responder) redirectFunc { return r.RedirectSeeOther })
+	suite.runRedirectToTests(303, func(r respond.Responder) redirectToFunc { return r.RedirectSeeOtherTo })
+}
+
+func (suite RespondSuite) TestRedirectFound() {
+	suite.runRedirectTests(302, func(r respond.Responder) redirectFunc { return r.RedirectFound })
+	suite.runRedirectToTests(302, func(r respond.Responder) redirectToFunc { return r.RedirectFoundTo })
+}
+
+func (suite RespondSuite) TestRedirectMovedPermanently() {
+	suite.runRedirectTests(301, func(r respond.Responder) redirectFunc { return r.RedirectMovedPermanently })
+	suite.runRedirectToTests(301, func(r respond.Responder) redirectToFunc { return r.RedirectMovedPermanentlyTo })
+}
+
 // Should allow you to write "raw" results by implementing io.Reader.
 func (suite RespondSuite) TestRaw_nil() {
 	type R struct {
@@ -498,7 +517,7 @@ func (suite RespondSuite) TestRaw_text() {
 	respond.To(w, req).Ok(result)
 	suite.assertStatus(w, 200)
 	suite.assertRaw(w, "hello world")
-	suite.assertHeader(w, "Content-Type", "application/octet-stream")
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
 	suite.assertHeader(w, "Content-Disposition", "inline")
 }
 
@@ -512,11 +531,11 @@ func (suite RespondSuite) TestRaw_binary() {
 	req := newRequest()
 
 	result := R{}
-	result.reader = newRawBytes([]byte{0x42, 0x43, 0x44})
+	result.reader = newRawBytes([]byte{0x00, 0x01, 0x02})
 
 	respond.To(w, req).Ok(result)
 	suite.assertStatus(w, 200)
-	suite.assertRaw(w, string([]byte{0x42, 0x43, 0x44}))
+	suite.assertRaw(w, string([]byte{0x00, 0x01, 0x02}))
 	suite.assertHeader(w, "Content-Type", "application/octet-stream")
 	suite.assertHeader(w, "Content-Disposition", "inline")
 }
@@ -558,7 +577,7 @@ func (suite RespondSuite) TestRaw_contentTypeEmpty() {
 	respond.To(w, req).Ok(result)
 	suite.assertStatus(w, 200)
 	suite.assertRaw(w, "Do you see what happens, Larry?")
-	suite.assertHeader(w, "Content-Type", "application/octet-stream")
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
 	suite.assertHeader(w, "Content-Disposition", "inline")
 }
 
@@ -579,7 +598,7 @@ func (suite RespondSuite) TestRaw_contentDisposition() {
 	respond.To(w, req).Ok(result)
 	suite.assertStatus(w, 200)
 	suite.assertRaw(w, "Do you see what happens, Larry?")
-	suite.assertHeader(w, "Content-Type", "application/octet-stream")
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
 	suite.assertHeader(w, "Content-Disposition", `attachment; filename="stranger.log"`)
 }
 
@@ -599,7 +618,7 @@ func (suite RespondSuite) TestRaw_contentDispositionEmpty() {
 	respond.To(w, req).Ok(result)
 	suite.assertStatus(w, 200)
 	suite.assertRaw(w, "Do you see what happens, Larry?")
-	suite.assertHeader(w, "Content-Type", "application/octet-stream")
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
 	suite.assertHeader(w, "Content-Disposition", "inline")
 }
 
@@ -879,7 +898,7 @@ func (suite RespondSuite) TestServe_noExt() {
 	buf.WriteString("hello world")
 	respond.To(w, req).Serve("foobarbaz", buf)
 
-	suite.assertHeader(w, "Content-Type", "application/octet-stream")
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
 	suite.assertHeader(w, "Content-Disposition", "inline")
 	suite.assertBody(w, "hello world")
 }
@@ -892,7 +911,7 @@ func (suite RespondSuite) TestServe_unknownExt() {
 	buf.WriteString("hello world")
 	respond.To(w, req).Serve("foobarbaz.goblins", buf)
 
-	suite.assertHeader(w, "Content-Type", "application/octet-stream")
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
 	suite.assertHeader(w, "Content-Disposition", "inline")
 	suite.assertBody(w, "hello world")
 }
@@ -952,6 +971,7 @@ func (suite RespondSuite) TestServe_error() {
 	suite.assertError(w, 504, "rats")
 }
 
+// The status has already been sent by the time we read the data, so the failure can't replace it.
 func (suite RespondSuite) TestServe_readerFail() {
 	w := newResponseWriter()
 	req := newRequest()
@@ -959,7 +979,9 @@ func (suite RespondSuite) TestServe_readerFail() {
 	reader := badReader{failureStatus: 403}
 	respond.To(w, req).Serve("foo.txt", reader)
 
-	suite.assertError(w, 403, "bad monkey")
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertEmptyBody(w)
 }
 
 func (suite RespondSuite) TestServeBytes_nil() {
@@ -1083,6 +1105,7 @@ func (suite RespondSuite) TestDownload_error() {
 	suite.assertError(w, 504, "rats")
 }
 
+// The status has already been sent by the time we read the data, so the failure can't replace it.
 func (suite RespondSuite) TestDownload_readerFail() {
 	w := newResponseWriter()
 	req := newRequest()
@@ -1090,7 +1113,9 @@ func (suite RespondSuite) TestDownload_readerFail() {
 	reader := badReader{failureStatus: 403}
 	respond.To(w, req).Download("foo.txt", reader)
 
-	suite.assertError(w, 403, "bad monkey")
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Type", "text/plain; charset=utf-8")
+	suite.assertEmptyBody(w)
 }
 
 func (suite RespondSuite) TestDownloadBytes_nil() {
@@ -1168,6 +1193,30 @@ func (suite RespondSuite) TestReply_redirect_forceStatus() {
 	suite.assertEmptyBody(w)
 }
 
+// Redirectors can pick their own 3XX status by implementing RedirectStatusReader.
+func (suite RespondSuite) TestReply_redirect_customStatus() {
+	for _, status := range []int{301, 302, 303, 307, 308} {
+		w := newResponseWriter()
+		req := newRequest()
+
+		respond.To(w, req).Reply(200, fakeStatusRedirector{URL: "https://google.com", Status: status})
+		suite.assertStatus(w, status)
+		suite.assertHeader(w, "Location", "https://google.com")
+	}
+}
+
+// Non-redirect statuses from a RedirectStatusReader should be ignored in favor of the default 307.
+func (suite RespondSuite) TestReply_redirect_customStatusInvalid() {
+	for _, status := range []int{0, 200, 300, 304, 305, 306, 404, 500} {
+		w := newResponseWriter()
+		req := newRequest()
+
+		respond.To(w, req).Reply(200, fakeStatusRedirector{URL: "https://google.com", Status: status})
+		suite.assertStatus(w, 307)
+		suite.assertHeader(w, "Location", "https://google.com")
+	}
+}
+
 func (suite RespondSuite) TestReply_redirect_error() {
 	w := newResponseWriter()
 	req := newRequest()
@@ -1200,6 +1249,50 @@ func (suite RespondSuite) runErrorTests(expectedCode int, errorResponse responde
 	suite.assertError(w, expectedCode, "foo bar 42")
 }
 
+type redirectFunc func(string, ...interface{})
+type redirectToFunc func(respond.Redirector, ...error)
+
+// runRedirectTests checks the standard behaviors of the printf-style redirect functions.
+func (suite RespondSuite) runRedirectTests(expectedStatus int, factory func(respond.Responder) redirectFunc) {
+	w := newResponseWriter()
+	factory(respond.To(w, newRequest()))("https://google.com")
+	suite.assertStatus(w, expectedStatus)
+	suite.assertHeader(w, "Location", "https://google.com")
+
+	w = newResponseWriter()
+	factory(respond.To(w, newRequest()))("https://google.com?q=%s", "hello")
+	suite.assertStatus(w, expectedStatus)
+	suite.assertHeader(w, "Location", "https://google.com?q=hello")
+
+	w = newResponseWriter()
+	factory(respond.To(w, newRequest()))("")
+	suite.assertStatus(w, 500)
+
+	w = newResponseWriter()
+	factory(respond.To(w, newRequest()))("%s%s", "", "")
+	suite.assertStatus(w, 500)
+}
+
+// runRedirectToTests checks the standard behaviors of the Redirector-based redirect functions.
+func (suite RespondSuite) runRedirectToTests(expectedStatus int, factory func(respond.Responder) redirectToFunc) {
+	w := newResponseWriter()
+	factory(respond.To(w, newRequest()))(fakeRedirector{URL: "https://google.com/foo"})
+	suite.assertStatus(w, expectedStatus)
+	suite.assertHeader(w, "Location", "https://google.com/foo")
+
+	w = newResponseWriter()
+	factory(respond.To(w, newRequest()))(nil)
+	suite.assertStatus(w, 500)
+
+	w = newResponseWriter()
+	factory(respond.To(w, newRequest()))(fakeRedirector{URL: "https://google.com/foo"}, errorWithStatus{
+		status:  403,
+		message: "nope",
+	})
+	suite.assertError(w, 403, "nope")
+	suite.assertHeader(w, "Location", "")
+}
+
 func (suite RespondSuite) assertStatus(res *mockResponseWriter, expected int) {
 	suite.Require().Equal(expected, res.StatusCode)
 }
@@ -1275,6 +1368,18 @@ func newRequest() *http.Request {
 	return &http.Request{}
 }
 
+// newHTTPRequest creates a fully formed request for the URI, setting any of the header name/value
+// pairs whose values aren't empty.
+func newHTTPRequest(method string, uri string, headers ...string) *http.Request {
+	req := httptest.NewRequest(method, uri, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		if headers[i+1] != "" {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+	}
+	return req
+}
+
 type mockUser struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -1338,6 +1443,19 @@ func (r fakeRedirector) Redirect() string {
 	return r.URL
 }
 
+type fakeStatusRedirector struct {
+	URL    string
+	Status int
+}
+
+func (r fakeStatusRedirector) Redirect() string {
+	return r.URL
+}
+
+func (r fakeStatusRedirector) RedirectStatus() int {
+	return r.Status
+}
+
 type rawContentReader struct {
 	reader io.ReadCloser
 }
@@ -0,0 +1,152 @@
+package respond_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+type nonceTemplateData struct {
+	Name  string
+	Nonce string
+}
+
+func (data *nonceTemplateData) SetCSPNonce(nonce string) {
+	data.Nonce = nonce
+}
+
+var nonceTemplate = template.Must(template.New("nonce").Parse(`<script nonce="{{ .Nonce }}">hello("{{ .Name }}")</script>`))
+
+var cspNoncePattern = regexp.MustCompile(`^default-src 'self'; script-src 'self' 'nonce-([A-Za-z0-9_-]+)'$`)
+
+func newSecurityFactory() *respond.Factory {
+	return respond.NewFactory(respond.WithSecurityHeaders(respond.SecurityHeaders{
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' {nonce}",
+		FrameAncestors:        "'none'",
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		PermissionsPolicy:     "camera=(), microphone=()",
+	}))
+}
+
+// By default, HTML responses shouldn't include any of the security headers.
+func (suite RespondSuite) TestSecurityHeaders_default() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).HTML("<p>Hello</p>")
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Security-Policy", "")
+	suite.assertHeader(w, "Strict-Transport-Security", "")
+	suite.assertHeader(w, "X-Content-Type-Options", "")
+	suite.Equal("", respond.To(w, newRequest()).CSPNonce())
+}
+
+func (suite RespondSuite) TestSecurityHeaders_html() {
+	w := newResponseWriter()
+	response := newSecurityFactory().To(w, newRequest())
+	response.HTML("<p>Hello</p>")
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, "<p>Hello</p>")
+	suite.assertHeader(w, "Content-Security-Policy", "default-src 'self'; script-src 'self' 'nonce-"+response.CSPNonce()+"'; frame-ancestors 'none'")
+	suite.assertHeader(w, "Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+	suite.assertHeader(w, "X-Content-Type-Options", "nosniff")
+	suite.assertHeader(w, "Referrer-Policy", "strict-origin-when-cross-origin")
+	suite.assertHeader(w, "Permissions-Policy", "camera=(), microphone=()")
+}
+
+// Each responder should get its own random nonce that shows up in both the header and the template.
+func (suite RespondSuite) TestSecurityHeaders_nonce() {
+	factory := respond.NewFactory(respond.WithSecurityHeaders(respond.SecurityHeaders{
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' {nonce}",
+	}))
+
+	w := newResponseWriter()
+	data := &nonceTemplateData{Name: "Bob"}
+	factory.To(w, newRequest()).HTMLTemplate(nonceTemplate, data)
+	suite.assertStatus(w, 200)
+
+	match := cspNoncePattern.FindStringSubmatch(w.Header().Get("Content-Security-Policy"))
+	suite.Require().Len(match, 2)
+	suite.Len(match[1], 24)
+	suite.Equal(match[1], data.Nonce)
+	suite.assertBody(w, `<script nonce="`+data.Nonce+`">hello("Bob")</script>`)
+
+	w = newResponseWriter()
+	otherData := &nonceTemplateData{Name: "Bob"}
+	factory.To(w, newRequest()).HTMLTemplate(nonceTemplate, otherData)
+	suite.NotEqual(data.Nonce, otherData.Nonce)
+	suite.NotEmpty(otherData.Nonce)
+}
+
+func (suite RespondSuite) TestSecurityHeaders_nonceMap() {
+	temp := template.Must(template.New("nonce").Parse(`<style nonce="{{ .CSPNonce }}"></style>`))
+
+	w := newResponseWriter()
+	response := newSecurityFactory().To(w, newRequest())
+	response.HTMLTemplate(temp, map[string]interface{}{"Name": "Bob"})
+	suite.assertStatus(w, 200)
+	suite.assertBody(w, `<style nonce="`+response.CSPNonce()+`"></style>`)
+
+	// Don't clobber a value the handler put there itself.
+	w = newResponseWriter()
+	newSecurityFactory().To(w, newRequest()).HTMLTemplate(temp, map[string]interface{}{"CSPNonce": "abc"})
+	suite.assertBody(w, `<style nonce="abc"></style>`)
+}
+
+// Policies w/o the "{nonce}" placeholder shouldn't bother generating one.
+func (suite RespondSuite) TestSecurityHeaders_noNonce() {
+	factory := respond.NewFactory(respond.WithSecurityHeaders(respond.SecurityHeaders{
+		FrameAncestors: "'self'",
+	}))
+
+	w := newResponseWriter()
+	data := &nonceTemplateData{Name: "Bob"}
+	response := factory.To(w, newRequest())
+	response.HTMLTemplate(nonceTemplate, data)
+	suite.Equal("", response.CSPNonce())
+	suite.Equal("", data.Nonce)
+	suite.assertHeader(w, "Content-Security-Policy", "frame-ancestors 'self'")
+	suite.assertHeader(w, "Strict-Transport-Security", "")
+	suite.assertHeader(w, "Referrer-Policy", "")
+	suite.assertHeader(w, "Permissions-Policy", "")
+	suite.assertHeader(w, "X-Content-Type-Options", "nosniff")
+}
+
+// JSON responses aren't HTML, so they shouldn't get the HTML security headers.
+func (suite RespondSuite) TestSecurityHeaders_json() {
+	w := newResponseWriter()
+	newSecurityFactory().To(w, newRequest()).Ok(mockUser{ID: 123, Name: "Bob"})
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "Content-Security-Policy", "")
+}
+
+// HTML error pages from the file server are still HTML, so they should get the headers, too.
+func (suite RespondSuite) TestSecurityHeaders_fileServerErrorPage() {
+	handler := respond.FileServer(newTestFS(), respond.FileServerOptions{Factory: newSecurityFactory()})
+	req := httptest.NewRequest(http.MethodGet, "/missing.txt", nil)
+	req.Header.Set("Accept", "text/html")
+
+	w := newResponseWriter()
+	handler.ServeHTTP(w, req)
+	suite.assertStatus(w, 404)
+	suite.True(strings.HasPrefix(w.Header().Get("Content-Security-Policy"), "default-src 'self'"))
+}
+
+// Raw files should always tell the browser not to sniff the content type, even w/o a policy.
+func (suite RespondSuite) TestSecurityHeaders_serveNoSniff() {
+	w := newResponseWriter()
+	respond.To(w, newRequest()).Serve("foo.txt", strings.NewReader("hello"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Content-Type-Options", "nosniff")
+
+	w = newResponseWriter()
+	respond.To(w, newRequest()).DownloadBytes("foo.html", []byte("<p>hello</p>"))
+	suite.assertStatus(w, 200)
+	suite.assertHeader(w, "X-Content-Type-Options", "nosniff")
+	suite.assertHeader(w, "Content-Security-Policy", "")
+}
@@ -0,0 +1,116 @@
+package respond
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerTimings collects named timing metrics (e.g. "db" or "cache") for a single request so that
+// responders can report them to the caller in a "Server-Timing" header, where they show up in the
+// browser's devtools. Use the ServerTiming() middleware to attach one to each request's context and
+// ServerTimingsFromContext() to grab it in your handlers.
+//
+// All of the methods are safe to call on a nil *ServerTimings, so your handlers can record timings
+// whether or not the middleware is in use.
+type ServerTimings struct {
+	mutex   sync.Mutex
+	metrics []serverTimingMetric
+	sent    int
+}
+
+// serverTimingMetric is a single named duration in the "Server-Timing" header.
+type serverTimingMetric struct {
+	name     string
+	duration time.Duration
+}
+
+// serverTimingsContextKey is the context key where the ServerTiming() middleware stores the request's timings.
+type serverTimingsContextKey struct{}
+
+// ServerTiming is middleware that attaches a ServerTimings collector to every request's context.
+// Responders will include everything recorded in it in the "Server-Timing" response header, along
+// w/ the time it took to marshal JSON ("marshal") or render HTML templates ("render"). Timings that
+// we only know once a streamed body is complete, such as "render", are sent as a trailer instead.
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), serverTimingsContextKey{}, &ServerTimings{})
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// ServerTimingsFromContext returns the timing collector that the ServerTiming() middleware attached
+// to the context. It's nil if the request didn't pass through the middleware.
+func ServerTimingsFromContext(ctx context.Context) *ServerTimings {
+	if ctx == nil {
+		return nil
+	}
+	timings, _ := ctx.Value(serverTimingsContextKey{}).(*ServerTimings)
+	return timings
+}
+
+// Record adds a metric w/ the given name and duration (e.g. "db", 12*time.Millisecond).
+func (timings *ServerTimings) Record(name string, duration time.Duration) {
+	if timings == nil {
+		return
+	}
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+	timings.metrics = append(timings.metrics, serverTimingMetric{name: name, duration: duration})
+}
+
+// Start begins timing the named metric, returning the function you call to stop the clock and record it.
+//
+//	defer respond.ServerTimingsFromContext(ctx).Start("db")()
+func (timings *ServerTimings) Start(name string) func() {
+	if timings == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		timings.Record(name, time.Since(start))
+	}
+}
+
+// serverTimings returns the timing collector for the request we're responding to, if any.
+func (r Responder) serverTimings() *ServerTimings {
+	if r.request == nil {
+		return nil
+	}
+	return ServerTimingsFromContext(r.request.Context())
+}
+
+// writeHeader sets the "Server-Timing" header to all of the metrics recorded so far.
+func (timings *ServerTimings) writeHeader(header http.Header) {
+	if value := timings.unsent(); value != "" {
+		header.Set("Server-Timing", value)
+	}
+}
+
+// writeTrailer sends any metrics recorded after we wrote the headers (i.e. while streaming the body)
+// as a "Server-Timing" trailer.
+func (timings *ServerTimings) writeTrailer(header http.Header) {
+	if value := timings.unsent(); value != "" {
+		header.Set(http.TrailerPrefix+"Server-Timing", value)
+	}
+}
+
+// unsent formats all of the metrics that we haven't sent to the caller yet, marking them as sent.
+func (timings *ServerTimings) unsent() string {
+	if timings == nil {
+		return ""
+	}
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+
+	entries := make([]string, 0, len(timings.metrics)-timings.sent)
+	for _, metric := range timings.metrics[timings.sent:] {
+		millis := float64(metric.duration) / float64(time.Millisecond)
+		entries = append(entries, metric.name+";dur="+strconv.FormatFloat(millis, 'f', -1, 64))
+	}
+	timings.sent = len(timings.metrics)
+	return strings.Join(entries, ", ")
+}
@@ -0,0 +1,89 @@
+package respond_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/monadicstack/respond"
+)
+
+var serverTimingPattern = regexp.MustCompile(`^[a-z]+;dur=[0-9.]+$`)
+
+// Without the middleware, there's nowhere to record timings, so we shouldn't send the header.
+func (suite RespondSuite) TestServerTiming_disabled() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timings := respond.ServerTimingsFromContext(req.Context())
+		suite.Nil(timings)
+		timings.Record("db", time.Millisecond)
+		timings.Start("cache")()
+		respond.To(w, req).Ok("hello")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/users/123"))
+	suite.Equal(200, w.Code)
+	suite.Equal("", w.Header().Get("Server-Timing"))
+	suite.Equal("", w.Result().Trailer.Get("Server-Timing"))
+}
+
+func (suite RespondSuite) TestServerTiming_json() {
+	handler := respond.ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timings := respond.ServerTimingsFromContext(req.Context())
+		timings.Record("db", 12500*time.Microsecond)
+		timings.Record("cache", 2*time.Millisecond)
+		respond.To(w, req).Ok(mockUser{ID: 123, Name: "Bob"})
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/users/123"))
+	suite.Equal(200, w.Code)
+
+	values := w.Header().Values("Server-Timing")
+	suite.Require().Len(values, 1)
+	suite.Regexp(`^db;dur=12.5, cache;dur=2, marshal;dur=[0-9.]+$`, values[0])
+	suite.Equal("", w.Result().Trailer.Get("Server-Timing"))
+}
+
+// Template render times are only known once we've streamed the body, so they should be sent as a trailer.
+func (suite RespondSuite) TestServerTiming_htmlTemplate() {
+	temp := template.Must(template.New("timing").Parse(`<p>{{ . }}</p>`))
+	handler := respond.ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer respond.ServerTimingsFromContext(req.Context()).Start("db")()
+		respond.ServerTimingsFromContext(req.Context()).Record("auth", time.Millisecond)
+		respond.To(w, req).HTMLTemplate(temp, "Bob")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/users/123"))
+	suite.Equal(200, w.Code)
+	suite.Equal("<p>Bob</p>", w.Body.String())
+	suite.Equal("auth;dur=1", w.Header().Get("Server-Timing"))
+	suite.Regexp(serverTimingPattern, w.Result().Trailer.Get("Server-Timing"))
+	suite.Contains(w.Result().Trailer.Get("Server-Timing"), "render;dur=")
+}
+
+// Timings should apply to all types of responses, not just JSON.
+func (suite RespondSuite) TestServerTiming_other() {
+	handler := respond.ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		respond.ServerTimingsFromContext(req.Context()).Record("db", 3*time.Millisecond)
+		respond.To(w, req).NoContent()
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/users/123"))
+	suite.Equal(204, w.Code)
+	suite.Equal("db;dur=3", w.Header().Get("Server-Timing"))
+
+	handler = respond.ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		respond.ServerTimingsFromContext(req.Context()).Record("s3", 40*time.Millisecond)
+		respond.To(w, req).ServeBytes("hello.txt", []byte("hello"))
+	}))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newHTTPRequest(http.MethodGet, "/users/123"))
+	suite.Equal(200, w.Code)
+	suite.Equal("s3;dur=40", w.Header().Get("Server-Timing"))
+}
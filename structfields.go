@@ -0,0 +1,328 @@
+package respond
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// structField describes one of the fields that encoding/json includes when marshaling a struct,
+// along w/ any "respond" tag directives that restrict who gets to see it.
+type structField struct {
+	// name is the JSON field name.
+	name string
+	// index is the field's index sequence for reflect.Value.FieldByIndex(), including the
+	// indices of any embedded structs that it was promoted from.
+	index []int
+	// typ is the Go type of the field.
+	typ reflect.Type
+	// tagged indicates that the field's name came from its json tag.
+	tagged bool
+	// omitEmpty indicates that the field has the json tag's "omitempty" option.
+	omitEmpty bool
+	// quoted indicates that the field has the json tag's "string" option.
+	quoted bool
+	// omit indicates that the field should never be marshaled (respond:"omit").
+	omit bool
+	// redact indicates that we replace the field's value rather than leaving it out when the
+	// caller isn't allowed to see it (respond:"redact").
+	redact bool
+	// views are the views that are allowed to see the field's real value (respond:"view=admin|support").
+	views []string
+	// empty overrides the factory's setting for marshaling a nil slice/map as [] or {} (respond:"empty")
+	// rather than null (respond:"nullable").
+	empty emptyMode
+}
+
+// structInfo is the cached metadata for a struct type that we need to redact and prune its JSON.
+type structInfo struct {
+	// fields are the struct's JSON fields in the order that encoding/json marshals them.
+	fields []structField
+	// byName maps JSON field names to their index in 'fields'.
+	byName map[string]int
+	// tagged indicates that at least one of the struct's own fields has a "respond" tag.
+	tagged bool
+}
+
+// structInfoCache maps struct types to their *structInfo.
+var structInfoCache sync.Map
+
+// structFields returns the JSON fields of the struct type, following the same rules as encoding/json:
+// json tag names, skipping "-" and unexported fields, and promoting the fields of embedded structs
+// (where shallower fields win, and ambiguous ones are dropped). Fields tagged w/ respond:"omit" are
+// never included. Results are cached since a type's fields never change.
+func structFields(t reflect.Type) *structInfo {
+	if cached, ok := structInfoCache.Load(t); ok {
+		return cached.(*structInfo)
+	}
+
+	info := &structInfo{byName: map[string]int{}}
+	for _, field := range dominantFields(collectStructFields(t)) {
+		if field.omit || field.redact || len(field.views) > 0 || field.empty != emptyInherit {
+			info.tagged = true
+		}
+		if field.omit {
+			continue
+		}
+		info.byName[field.name] = len(info.fields)
+		info.fields = append(info.fields, field)
+	}
+	cached, _ := structInfoCache.LoadOrStore(t, info)
+	return cached.(*structInfo)
+}
+
+// collectStructFields gathers every candidate JSON field of the struct type, breadth-first through its
+// embedded structs, the same way that encoding/json does. There may be multiple fields w/ the same name.
+func collectStructFields(t reflect.Type) []structField {
+	type embeddedStruct struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []structField
+	visited := map[reflect.Type]bool{}
+	next := []embeddedStruct{{typ: t}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+
+		// The same struct embedded more than once at the same depth makes all of its fields
+		// ambiguous, so we add them twice to make sure that dominantFields() drops them.
+		count := map[reflect.Type]int{}
+		for _, embedded := range current {
+			count[embedded.typ]++
+		}
+
+		for _, embedded := range current {
+			if visited[embedded.typ] {
+				continue
+			}
+			visited[embedded.typ] = true
+
+			for i := 0; i < embedded.typ.NumField(); i++ {
+				sf := embedded.typ.Field(i)
+				field, ok := newStructField(sf)
+				if !ok {
+					continue
+				}
+				field.index = append(append(make([]int, 0, len(embedded.index)+1), embedded.index...), i)
+
+				if sf.Anonymous && !field.tagged {
+					fieldType := sf.Type
+					if fieldType.Kind() == reflect.Ptr {
+						fieldType = fieldType.Elem()
+					}
+					if fieldType.Kind() == reflect.Struct {
+						next = append(next, embeddedStruct{typ: fieldType, index: field.index})
+						continue
+					}
+				}
+
+				fields = append(fields, field)
+				if count[embedded.typ] > 1 {
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// newStructField parses the struct field's json/respond tags. It returns false if encoding/json
+// doesn't marshal the field at all.
+func newStructField(sf reflect.StructField) (structField, bool) {
+	if sf.Anonymous {
+		fieldType := sf.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if sf.PkgPath != "" && fieldType.Kind() != reflect.Struct {
+			return structField{}, false // unexported, non-struct embedded fields aren't marshaled
+		}
+	} else if sf.PkgPath != "" {
+		return structField{}, false // unexported
+	}
+
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return structField{}, false
+	}
+
+	field := structField{name: sf.Name, typ: sf.Type}
+	options := strings.Split(tag, ",")
+	if options[0] != "" {
+		field.name = options[0]
+		field.tagged = true
+	}
+	for _, option := range options[1:] {
+		switch option {
+		case "omitempty":
+			field.omitEmpty = true
+		case "string":
+			field.quoted = isQuotable(sf.Type)
+		}
+	}
+
+	if tag, ok := sf.Tag.Lookup("respond"); ok {
+		parseRespondTag(&field, tag)
+	}
+	return field, true
+}
+
+// parseRespondTag applies the comma-separated directives in a field's "respond" tag (e.g.
+// `respond:"redact,view=admin"`). We'd rather leak nothing than something, so a directive that
+// we don't recognize (e.g. a typo) omits the field entirely.
+func parseRespondTag(field *structField, tag string) {
+	for _, directive := range strings.Split(tag, ",") {
+		directive = strings.TrimSpace(directive)
+		switch {
+		case directive == "":
+			continue
+		case directive == "omit":
+			field.omit = true
+		case directive == "redact":
+			field.redact = true
+		case directive == "empty":
+			field.empty = emptyAlways
+		case directive == "nullable":
+			field.empty = emptyNever
+		case strings.HasPrefix(directive, "view="):
+			for _, view := range strings.Split(strings.TrimPrefix(directive, "view="), "|") {
+				if view = strings.TrimSpace(view); view != "" {
+					field.views = append(field.views, view)
+				}
+			}
+		default:
+			field.omit = true
+		}
+	}
+}
+
+// dominantFields resolves conflicts between fields w/ the same name the way encoding/json does: the
+// shallowest field wins, w/ tagged fields winning ties. If there's still a tie, none of them are
+// marshaled. The remaining fields are returned in the order encoding/json marshals them.
+func dominantFields(fields []structField) []structField {
+	byName := map[string][]structField{}
+	for _, field := range fields {
+		byName[field.name] = append(byName[field.name], field)
+	}
+
+	dominant := make([]structField, 0, len(byName))
+	for _, candidates := range byName {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if len(candidates[i].index) != len(candidates[j].index) {
+				return len(candidates[i].index) < len(candidates[j].index)
+			}
+			return candidates[i].tagged && !candidates[j].tagged
+		})
+		if len(candidates) > 1 &&
+			len(candidates[0].index) == len(candidates[1].index) &&
+			candidates[0].tagged == candidates[1].tagged {
+			continue
+		}
+		dominant = append(dominant, candidates[0])
+	}
+
+	sort.Slice(dominant, func(i, j int) bool {
+		a, b := dominant[i].index, dominant[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return dominant
+}
+
+// visibleTo determines if the given view is allowed to see the field's real value. Fields w/o any
+// views are visible to everyone unless they're redacted.
+func (field structField) visibleTo(view string) bool {
+	if len(field.views) == 0 {
+		return !field.redact
+	}
+	for _, allowed := range field.views {
+		if allowed == view {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the field's value in the struct. It returns false if the field was promoted from
+// an embedded struct pointer that's nil, in which case encoding/json skips it.
+func (field structField) value(v reflect.Value) (reflect.Value, bool) {
+	for i, index := range field.index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(index)
+	}
+	return v, true
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonAppenderType    = reflect.TypeOf((*JSONAppender)(nil)).Elem()
+	jsonMarshalerToType = reflect.TypeOf((*JSONMarshalerTo)(nil)).Elem()
+)
+
+// hasCustomJSON determines if the type decides for itself how it's marshaled to JSON.
+func hasCustomJSON(t reflect.Type) bool {
+	ptr := reflect.PtrTo(t)
+	return t.Implements(jsonMarshalerType) || ptr.Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || ptr.Implements(textMarshalerType) ||
+		hasJSONFastPath(t)
+}
+
+// hasJSONFastPath determines if the type can write its own JSON w/o encoding/json (i.e. it's a
+// JSONAppender or JSONMarshalerTo), which only happens when we're the ones writing it.
+func hasJSONFastPath(t reflect.Type) bool {
+	return t.Implements(jsonAppenderType) || t.Implements(jsonMarshalerToType)
+}
+
+// isQuotable determines if the json tag's "string" option applies to a field of this type.
+func isQuotable(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	default:
+		return false
+	}
+}
+
+// isEmptyValue determines if the value is empty according to the json tag's "omitempty" option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
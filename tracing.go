@@ -0,0 +1,62 @@
+package respond
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer lets you annotate your tracing spans (OpenTelemetry, etc.) w/ the details of every response
+// that a factory's responders write, w/o this package depending on any particular tracing library.
+// The "otel" submodule contains an OpenTelemetry implementation you can use as-is or as a reference.
+type Tracer interface {
+	// TraceResponse is called after every response is written, using the request's context.
+	TraceResponse(ctx context.Context, info TraceInfo)
+	// TraceFail is called whenever the responder fails, receiving the original error you supplied
+	// (and therefore its entire chain of wrapped errors), not just the message we send to the caller.
+	TraceFail(ctx context.Context, err error)
+}
+
+// TraceInfo describes a response that was written so that a Tracer can annotate its span.
+type TraceInfo struct {
+	// Status is the HTTP status code we sent.
+	Status int
+	// ContentType is the value of the "Content-Type" header we sent, if any.
+	ContentType string
+	// BytesWritten is the size of the response body.
+	BytesWritten int64
+	// Err is the original error the responder failed with, if this is an error response.
+	Err error
+	// StatusSource is the error in Err's chain that determined the status; the one w/ a Status(),
+	// StatusCode(), or Code() function. It's nil if we couldn't find one and failed w/ a 500.
+	StatusSource error
+	// WriteErr is the error that occurred while writing the response body, if any.
+	WriteErr error
+}
+
+// WithTracer reports every response (and failure) from the factory's responders to the tracer.
+func WithTracer(tracer Tracer) FactoryOption {
+	return func(factory *Factory) {
+		WithAfterWrite(func(event WriteEvent) {
+			tracer.TraceResponse(requestContext(event.Request), TraceInfo{
+				Status:       event.Status,
+				ContentType:  event.Header.Get("Content-Type"),
+				BytesWritten: event.BytesWritten,
+				Err:          event.Failure,
+				StatusSource: statusSource(event.Failure),
+				WriteErr:     event.Err,
+			})
+		})(factory)
+
+		WithOnFail(func(req *http.Request, err error) {
+			tracer.TraceFail(requestContext(req), err)
+		})(factory)
+	}
+}
+
+// requestContext returns the request's context, or an empty context if there's no request.
+func requestContext(req *http.Request) context.Context {
+	if req == nil {
+		return context.Background()
+	}
+	return req.Context()
+}
@@ -0,0 +1,96 @@
+package respond_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/monadicstack/respond"
+)
+
+// fakeTracer records all of the responses/failures it was asked to trace.
+type fakeTracer struct {
+	responses []respond.TraceInfo
+	fails     []error
+	contexts  []context.Context
+}
+
+func (tracer *fakeTracer) TraceResponse(ctx context.Context, info respond.TraceInfo) {
+	tracer.responses = append(tracer.responses, info)
+	tracer.contexts = append(tracer.contexts, ctx)
+}
+
+func (tracer *fakeTracer) TraceFail(ctx context.Context, err error) {
+	tracer.fails = append(tracer.fails, err)
+}
+
+type tracingContextKey struct{}
+
+func (suite RespondSuite) TestTracer_success() {
+	tracer := &fakeTracer{}
+	factory := respond.NewFactory(respond.WithTracer(tracer))
+	req := httptest.NewRequest(http.MethodGet, "/users/123", nil)
+	req = req.WithContext(context.WithValue(req.Context(), tracingContextKey{}, "span"))
+
+	w := newResponseWriter()
+	factory.To(w, req).Ok(mockUser{ID: 123, Name: "Bob"})
+	suite.assertStatus(w, 200)
+	suite.Require().Len(tracer.responses, 1)
+	suite.Equal(respond.TraceInfo{
+		Status:       200,
+		ContentType:  "application/json",
+		BytesWritten: int64(len(w.Body)),
+	}, tracer.responses[0])
+	suite.Equal("span", tracer.contexts[0].Value(tracingContextKey{}))
+	suite.Len(tracer.fails, 0)
+}
+
+// Failures should carry the original error chain as well as the error that determined the status.
+func (suite RespondSuite) TestTracer_fail() {
+	tracer := &fakeTracer{}
+	factory := respond.NewFactory(respond.WithTracer(tracer))
+	rootErr := errorWithStatus{status: 404, message: "not found"}
+	err := fmt.Errorf("loading user: %w", rootErr)
+
+	w := newResponseWriter()
+	factory.To(w, newRequest()).Ok(nil, err)
+	suite.assertError(w, 404, "not found")
+
+	suite.Require().Len(tracer.fails, 1)
+	suite.Same(err, tracer.fails[0])
+	suite.Require().Len(tracer.responses, 1)
+	suite.Equal(404, tracer.responses[0].Status)
+	suite.Equal("application/json", tracer.responses[0].ContentType)
+	suite.Equal(int64(len(w.Body)), tracer.responses[0].BytesWritten)
+	suite.Same(err, tracer.responses[0].Err)
+	suite.Equal(rootErr, tracer.responses[0].StatusSource)
+}
+
+// Errors w/o any status information should have no status source; they're just 500s.
+func (suite RespondSuite) TestTracer_failUnknown() {
+	tracer := &fakeTracer{}
+	err := errors.New("boom")
+
+	w := newResponseWriter()
+	respond.NewFactory(respond.WithTracer(tracer)).To(w, nil).Fail(err)
+	suite.assertError(w, 500, "boom")
+	suite.Require().Len(tracer.responses, 1)
+	suite.Equal(err, tracer.responses[0].Err)
+	suite.Nil(tracer.responses[0].StatusSource)
+	suite.Equal([]error{err}, tracer.fails)
+}
+
+func (suite RespondSuite) TestTracer_raw() {
+	tracer := &fakeTracer{}
+	w := newResponseWriter()
+	respond.NewFactory(respond.WithTracer(tracer)).To(w, newRequest()).ServeBytes("hello.txt", []byte("hello"))
+	suite.assertStatus(w, 200)
+	suite.Require().Len(tracer.responses, 1)
+	suite.Equal(respond.TraceInfo{
+		Status:       200,
+		ContentType:  "text/plain; charset=utf-8",
+		BytesWritten: 5,
+	}, tracer.responses[0])
+}